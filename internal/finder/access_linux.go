@@ -0,0 +1,14 @@
+package finder
+
+import "syscall"
+
+const (
+	atFdCwd   = -0x64 // AT_FDCWD，相对当前工作目录解析路径
+	atEAccess = 0x200 // AT_EACCESS，使用有效 uid/gid 进行检查
+)
+
+// checkAccess 使用 faccessat(2) 按当前进程的有效凭据检查访问权限
+// 该检查会同时考虑属主、属组以及 ACL，而不仅仅是权限位
+func checkAccess(path string, mode uint32) bool {
+	return syscall.Faccessat(atFdCwd, path, mode, atEAccess) == nil
+}
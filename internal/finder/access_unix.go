@@ -0,0 +1,10 @@
+//go:build unix && !linux
+
+package finder
+
+import "syscall"
+
+// checkAccess 使用 access(2) 按当前进程的凭据检查访问权限
+func checkAccess(path string, mode uint32) bool {
+	return syscall.Access(path, mode) == nil
+}
@@ -0,0 +1,57 @@
+package finder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// checkAccess 在 Windows 上通过实际打开文件来判断当前用户的访问权限
+// Windows 的 ACL 无法通过权限位表达，直接尝试打开是最可靠的方式
+func checkAccess(path string, mode uint32) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	if mode&accessRead != 0 {
+		f, err := os.Open(path)
+		if err != nil {
+			return false
+		}
+		f.Close()
+	}
+
+	if mode&accessWrite != 0 {
+		if info.Mode().Perm()&0200 == 0 {
+			return false
+		}
+		if !info.IsDir() {
+			f, err := os.OpenFile(path, os.O_WRONLY, 0)
+			if err != nil {
+				return false
+			}
+			f.Close()
+		}
+	}
+
+	if mode&accessExecute != 0 && !info.IsDir() {
+		ext := strings.ToLower(filepath.Ext(path))
+		pathExt := os.Getenv("PATHEXT")
+		if pathExt == "" {
+			pathExt = ".com;.exe;.bat;.cmd"
+		}
+		found := false
+		for _, e := range strings.Split(strings.ToLower(pathExt), ";") {
+			if e != "" && e == ext {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
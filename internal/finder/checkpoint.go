@@ -0,0 +1,400 @@
+package finder
+
+import (
+	"encoding/json"
+	"file-finder/internal/search"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// checkpointVersion 检查点文件格式版本
+const checkpointVersion = 3
+
+// Checkpoint 可恢复搜索的检查点
+// 记录每个搜索阶段已完成的目录和已输出的结果，-resume 时跳过已完成的目录，
+// 并与之前的结果合并，最终输出与未中断时一致
+// 整棵子树完成的目录只保留其本身的记录，删除其下各目录的记录，文件中只保存遍历的边界
+type Checkpoint struct {
+	Version int                         `json:"version"`
+	Args    []string                    `json:"args"`    // 原始命令行参数，-resume 时重新解析
+	Created time.Time                   `json:"created"` // 首次创建时间
+	Updated time.Time                   `json:"updated"` // 最近一次保存时间
+	Phases  map[string]*checkpointPhase `json:"phases"`  // 按搜索阶段和起始目录区分
+
+	path  string
+	mu    sync.Mutex
+	dirty bool
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// checkpointPhase 单个搜索阶段（内容、权限、时间搜索）的进度
+type checkpointPhase struct {
+	Done    bool                        `json:"done"`    // 阶段是否已完整结束
+	Dirs    map[string]bool             `json:"dirs"`    // 已完成的目录：false 为目录中的条目已处理完，true 为整棵子树已处理完
+	Results map[string]checkpointResult `json:"results"` // 已找到的结果，按路径索引
+
+	cp *Checkpoint
+}
+
+// checkpointResult 检查点中保存的结果，只保存输出需要且无法由其他字段生成的信息
+// 权限、时间搜索只需要路径，保存为空对象；内容搜索的匹配行上下文中每一行只保存一次
+type checkpointResult struct {
+	Size        int64             `json:"size,omitempty"`
+	ModTime     string            `json:"mtime,omitempty"`
+	Permissions string            `json:"perm,omitempty"`
+	Type        string            `json:"type,omitempty"`
+	MimeType    string            `json:"mime,omitempty"`
+	LinkTarget  string            `json:"link,omitempty"`
+	LinkBroken  bool              `json:"broken,omitempty"`
+	MatchType   string            `json:"match,omitempty"`
+	Lines       map[int]string    `json:"lines,omitempty"` // 匹配行及上下文的内容，按行号索引
+	Matches     []checkpointMatch `json:"matches,omitempty"`
+}
+
+// checkpointMatch 一个匹配行，内容和上下文从 checkpointResult.Lines 中取得
+type checkpointMatch struct {
+	Line      int   `json:"line"`
+	Positions []int `json:"pos"`
+	Start     int   `json:"start"` // 上下文第一行的行号
+	End       int   `json:"end"`   // 上下文最后一行的行号
+}
+
+// compactResult 转换为检查点中保存的形式
+func compactResult(info FileInfo) checkpointResult {
+	r := checkpointResult{
+		Size:        info.Size,
+		ModTime:     info.ModTime,
+		Permissions: info.Permissions,
+		Type:        info.Type,
+		MimeType:    info.MimeType,
+		LinkTarget:  info.LinkTarget,
+		LinkBroken:  info.LinkBroken,
+		MatchType:   info.MatchType,
+	}
+	for _, match := range info.Matches {
+		if r.Lines == nil {
+			r.Lines = make(map[int]string)
+		}
+		for i, line := range match.Context {
+			r.Lines[match.ContextStart+i] = line
+		}
+		r.Lines[match.LineMatch.LineNumber] = match.LineMatch.Line
+		r.Matches = append(r.Matches, checkpointMatch{
+			Line:      match.LineMatch.LineNumber,
+			Positions: match.LineMatch.Positions,
+			Start:     match.ContextStart,
+			End:       match.ContextStart + len(match.Context) - 1,
+		})
+	}
+	return r
+}
+
+// expand 还原为 FileInfo，行号、匹配次数、上下文和内容预览由匹配行重新生成
+func (r checkpointResult) expand(path string) FileInfo {
+	info := FileInfo{
+		Path:        path,
+		Size:        r.Size,
+		ModTime:     r.ModTime,
+		Permissions: r.Permissions,
+		Type:        r.Type,
+		MimeType:    r.MimeType,
+		LinkTarget:  r.LinkTarget,
+		LinkBroken:  r.LinkBroken,
+		MatchType:   r.MatchType,
+	}
+	if len(r.Matches) == 0 {
+		return info
+	}
+
+	matches := make([]search.ContextMatch, len(r.Matches))
+	for i, m := range r.Matches {
+		var context []string
+		for n := m.Start; n <= m.End; n++ {
+			context = append(context, r.Lines[n])
+		}
+		matches[i] = search.ContextMatch{
+			LineMatch: search.LineMatch{
+				LineNumber: m.Line,
+				Line:       r.Lines[m.Line],
+				Positions:  m.Positions,
+				Count:      len(m.Positions),
+			},
+			Context:      context,
+			ContextStart: m.Start,
+		}
+	}
+	info.setMatches(matches)
+	return info
+}
+
+// NewCheckpoint 创建检查点，args 为不含 -checkpoint/-resume 的命令行参数
+func NewCheckpoint(path string, args []string) *Checkpoint {
+	now := time.Now()
+	return &Checkpoint{
+		Version: checkpointVersion,
+		Args:    args,
+		Created: now,
+		Updated: now,
+		Phases:  make(map[string]*checkpointPhase),
+		path:    path,
+		dirty:   true,
+	}
+}
+
+// LoadCheckpoint 读取检查点文件
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cp := &Checkpoint{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("检查点文件格式错误: %v", err)
+	}
+	if cp.Version != checkpointVersion {
+		return nil, fmt.Errorf("不支持的检查点版本: %d", cp.Version)
+	}
+
+	cp.path = path
+	if cp.Phases == nil {
+		cp.Phases = make(map[string]*checkpointPhase)
+	}
+	for _, phase := range cp.Phases {
+		phase.cp = cp
+		if phase.Dirs == nil {
+			phase.Dirs = make(map[string]bool)
+		}
+		if phase.Results == nil {
+			phase.Results = make(map[string]checkpointResult)
+		}
+	}
+	return cp, nil
+}
+
+// Start 每隔 interval 将有变化的检查点写入文件，直到调用 Close
+func (c *Checkpoint) Start(interval time.Duration) {
+	c.stop = make(chan struct{})
+	c.done = make(chan struct{})
+	go func() {
+		defer close(c.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := c.Save(); err != nil {
+					logAndPrint("[检查点] 保存失败: %v", err)
+				}
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Close 停止定时保存并写入最终状态
+func (c *Checkpoint) Close() error {
+	if c.stop != nil {
+		close(c.stop)
+		<-c.done
+		c.stop = nil
+	}
+	return c.Save()
+}
+
+// Save 将检查点写入文件，先写临时文件再重命名，避免中断时留下不完整的文件
+func (c *Checkpoint) Save() error {
+	c.mu.Lock()
+	if !c.dirty {
+		c.mu.Unlock()
+		return nil
+	}
+	c.Updated = time.Now()
+	data, err := json.Marshal(c)
+	c.dirty = false
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
+
+// phase 获取搜索阶段的进度，不存在时创建；c 为 nil（未启用检查点）时返回 nil
+func (c *Checkpoint) phase(name string) *checkpointPhase {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.Phases[name]
+	if !ok {
+		p = &checkpointPhase{
+			Dirs:    make(map[string]bool),
+			Results: make(map[string]checkpointResult),
+			cp:      c,
+		}
+		c.Phases[name] = p
+		c.dirty = true
+	}
+	return p
+}
+
+// isDone 阶段是否已完整结束
+func (p *checkpointPhase) isDone() bool {
+	if p == nil {
+		return false
+	}
+	p.cp.mu.Lock()
+	defer p.cp.mu.Unlock()
+	return p.Done
+}
+
+// finish 标记阶段已完整结束，已完成目录的记录不再需要
+func (p *checkpointPhase) finish() {
+	if p == nil {
+		return
+	}
+	p.cp.mu.Lock()
+	p.Done = true
+	p.Dirs = make(map[string]bool)
+	p.cp.dirty = true
+	p.cp.mu.Unlock()
+}
+
+// dirDone 目录中的条目是否都已处理完，以及整棵子树是否都已处理完
+func (p *checkpointPhase) dirDone(dir string) (entries, tree bool) {
+	if p == nil {
+		return false, false
+	}
+	p.cp.mu.Lock()
+	defer p.cp.mu.Unlock()
+	tree, entries = p.Dirs[dir]
+	return entries, tree
+}
+
+// markDir 记录目录中的条目都已处理完
+func (p *checkpointPhase) markDir(dir string) {
+	p.cp.mu.Lock()
+	if _, ok := p.Dirs[dir]; !ok {
+		p.Dirs[dir] = false
+		p.cp.dirty = true
+	}
+	p.cp.mu.Unlock()
+}
+
+// markTree 记录目录的整棵子树都已处理完，子目录的记录不再需要
+// 子目录的子树总是先于父目录完成，删除直接子目录的记录即可清除整棵子树
+func (p *checkpointPhase) markTree(dir string, children []string) {
+	p.cp.mu.Lock()
+	p.Dirs[dir] = true
+	for _, child := range children {
+		delete(p.Dirs, child)
+	}
+	p.cp.dirty = true
+	p.cp.mu.Unlock()
+}
+
+// addResult 记录找到的结果
+func (p *checkpointPhase) addResult(info FileInfo) {
+	if p == nil {
+		return
+	}
+	r := compactResult(info)
+	p.cp.mu.Lock()
+	p.Results[info.Path] = r
+	p.cp.dirty = true
+	p.cp.mu.Unlock()
+}
+
+// results 返回之前已找到的结果
+func (p *checkpointPhase) results() map[string]FileInfo {
+	results := make(map[string]FileInfo)
+	if p == nil {
+		return results
+	}
+	p.cp.mu.Lock()
+	defer p.cp.mu.Unlock()
+	for path, r := range p.Results {
+		results[path] = r.expand(path)
+	}
+	return results
+}
+
+// paths 返回之前已找到的结果路径，按路径排序
+func (p *checkpointPhase) paths() []string {
+	var paths []string
+	for path := range p.results() {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// dirTracker 跟踪单个目录及其子树的处理进度
+// 目录读取完毕且输出的条目都被调用方处理完后，记录目录的条目已完成；
+// 之后加入队列的子目录的子树也都完成时，记录整棵子树已完成并通知父目录
+type dirTracker struct {
+	path     string
+	entries  int64    // 未处理完的条目数，读取目录期间额外持有 1
+	subtree  int64    // 未完成的部分：目录自身的条目持有 1，每个加入队列的子目录持有 1
+	children []string // 子目录路径，只在读取目录期间追加，子树完成时删除它们的记录
+	parent   *dirTracker
+	phase    *checkpointPhase
+}
+
+// newDirTracker 创建目录的进度跟踪，parent 为 nil 表示起始目录
+func newDirTracker(path string, parent *dirTracker, phase *checkpointPhase) *dirTracker {
+	return &dirTracker{path: path, entries: 1, subtree: 1, parent: parent, phase: phase}
+}
+
+// add 登记一个输出的条目
+func (t *dirTracker) add() {
+	atomic.AddInt64(&t.entries, 1)
+}
+
+// done 一个条目处理完毕（或目录读取完毕）
+func (t *dirTracker) done() {
+	if atomic.AddInt64(&t.entries, -1) == 0 {
+		t.phase.markDir(t.path)
+		t.release()
+	}
+}
+
+// addChild 登记一个子目录，queued 为 false 表示子树在之前的搜索中已完成、不再加入队列
+func (t *dirTracker) addChild(path string, queued bool) {
+	t.children = append(t.children, path)
+	if queued {
+		atomic.AddInt64(&t.subtree, 1)
+	}
+}
+
+// release 目录自身的条目或一个子目录的子树处理完毕
+func (t *dirTracker) release() {
+	if atomic.AddInt64(&t.subtree, -1) == 0 {
+		t.phase.markTree(t.path, t.children)
+		if t.parent != nil {
+			t.parent.release()
+		}
+	}
+}
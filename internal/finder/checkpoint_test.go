@@ -0,0 +1,140 @@
+package finder
+
+import (
+	"context"
+	"file-finder/internal/search"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// walkAll 使用检查点遍历 root，对每个条目调用 Done，返回输出的条目路径
+func walkAll(t *testing.T, root string, phase *checkpointPhase) []string {
+	t.Helper()
+	entries, err := NewWalker(newTestConfig(root)).resume(phase).Walk(context.Background(), root)
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	var paths []string
+	for entry := range entries {
+		paths = append(paths, entry.Path)
+		entry.Done()
+	}
+	return paths
+}
+
+// TestCheckpointKeepsFrontier 子树完成后只保留子树根目录的记录，遍历完成时只剩起始目录
+func TestCheckpointKeepsFrontier(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root, 5, 3)
+	makeTree(t, filepath.Join(root, "dir0000"), 4, 2)
+
+	phase := NewCheckpoint(filepath.Join(t.TempDir(), "scan.ckpt"), nil).phase("test")
+	walkAll(t, root, phase)
+
+	if len(phase.Dirs) != 1 || !phase.Dirs[root] {
+		t.Errorf("遍历完成后 Dirs = %v, want 只有 %s: true", phase.Dirs, root)
+	}
+
+	// 整棵树已完成，再次遍历只输出起始目录本身
+	if paths := walkAll(t, root, phase); len(paths) != 1 || paths[0] != root {
+		t.Errorf("再次遍历输出 %v, want [%s]", paths, root)
+	}
+}
+
+// TestDirTrackerFrontier 子目录未完成时父目录只记录条目已完成，子目录完成后删除子目录的记录
+func TestDirTrackerFrontier(t *testing.T) {
+	phase := NewCheckpoint(filepath.Join(t.TempDir(), "scan.ckpt"), nil).phase("test")
+
+	parent := newDirTracker("/r", nil, phase)
+	a := newDirTracker("/r/a", parent, phase)
+	b := newDirTracker("/r/b", parent, phase)
+	parent.addChild("/r/a", true)
+	parent.addChild("/r/b", true)
+	parent.addChild("/r/c", false) // 之前已完成的子树
+	phase.Dirs["/r/c"] = true
+
+	parent.add()
+	parent.done() // 读取完毕，还有一个条目未处理
+	if _, ok := phase.Dirs["/r"]; ok {
+		t.Fatalf("条目未处理完时记录了 /r")
+	}
+	parent.done()
+	if tree, ok := phase.Dirs["/r"]; !ok || tree {
+		t.Fatalf("条目处理完后 Dirs[/r] = %v, %v, want false, true", tree, ok)
+	}
+
+	a.done()
+	if !phase.Dirs["/r/a"] {
+		t.Fatalf("/r/a 的子树完成后未记录")
+	}
+	b.done()
+
+	want := map[string]bool{"/r": true}
+	if len(phase.Dirs) != len(want) || !phase.Dirs["/r"] {
+		t.Errorf("全部完成后 Dirs = %v, want %v", phase.Dirs, want)
+	}
+}
+
+func TestCheckpointResultRoundTrip(t *testing.T) {
+	lines := strings.Split("a\nflag one\nb\nc\nflag two FLAG\nd\ne\nf\ng\nflag three", "\n")
+	contentResult := FileInfo{
+		Path: "/r/notes.txt", Size: 60, ModTime: "2024-01-02 03:04:05", Permissions: "-rw-r--r--",
+		Type: "f", MimeType: "text/plain", MatchType: "content",
+	}
+	contentResult.setMatches(search.NewContextSearchRange("flag", false, 1, 2).SearchWithContext(lines))
+	if len(contentResult.Matches) != 3 || contentResult.MatchCount != 4 {
+		t.Fatalf("匹配 %d 行 %d 处, want 3 行 4 处", len(contentResult.Matches), contentResult.MatchCount)
+	}
+
+	tests := []struct {
+		name string
+		info FileInfo
+	}{
+		{"内容匹配（上下文重叠、多处匹配、文件末尾）", contentResult},
+		{"文件名匹配", FileInfo{Path: "/r/flag.txt", Size: 3, Type: "f", MimeType: "text/plain", MatchType: "filename"}},
+		{"失效的符号链接", FileInfo{Path: "/r/link", Type: "l", LinkTarget: "missing", LinkBroken: true}},
+		{"只有路径", FileInfo{Path: "/r/suid"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := compactResult(tt.info).expand(tt.info.Path)
+			if !reflect.DeepEqual(got, tt.info) {
+				t.Errorf("还原结果不一致\n got %+v\nwant %+v", got, tt.info)
+			}
+		})
+	}
+}
+
+func TestCheckpointSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scan.ckpt")
+	cp := NewCheckpoint(path, []string{"-k", "flag", "-m", "content"})
+	phase := cp.phase("content:/r")
+	phase.markDir("/r/a")
+	phase.markTree("/r/b", nil)
+	info := FileInfo{Path: "/r/a/x.txt", Size: 1, Type: "f", MatchType: "content"}
+	info.setMatches(search.NewContextSearch("flag", false, 0).SearchWithContext([]string{"flag"}))
+	phase.addResult(info)
+	if err := cp.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Args, cp.Args) {
+		t.Errorf("Args = %v, want %v", loaded.Args, cp.Args)
+	}
+	p := loaded.phase("content:/r")
+	if entries, tree := p.dirDone("/r/a"); !entries || tree {
+		t.Errorf("dirDone(/r/a) = %v, %v, want true, false", entries, tree)
+	}
+	if entries, tree := p.dirDone("/r/b"); !entries || !tree {
+		t.Errorf("dirDone(/r/b) = %v, %v, want true, true", entries, tree)
+	}
+	if got := p.results()[info.Path]; !reflect.DeepEqual(got, info) {
+		t.Errorf("results() = %+v, want %+v", got, info)
+	}
+}
@@ -1,37 +1,182 @@
 package finder
 
+import (
+	"file-finder/internal/search"
+	"file-finder/internal/utils"
+	"runtime"
+	"sync"
+)
+
 type FileInfo struct {
 	Path        string
 	Size        int64
 	ModTime     string
 	Permissions string
+	Type        string // 文件类型代码：f, d, l, s, p, b, c
+	MimeType    string // 根据文件头检测的 MIME 类型
+	LinkTarget  string // 符号链接指向的路径
+	LinkBroken  bool   // 符号链接是否已失效
 	Content     string
 	// 新增匹配信息
-	MatchType  string   // 匹配类型：filename, content, both
-	MatchLines []int    // 匹配的行号
-	MatchCount int      // 匹配次数
-	Context    []string // 上下文内容
+	MatchType  string                // 匹配类型：filename, content, both
+	MatchLines []int                 // 匹配的行号
+	MatchCount int                   // 匹配次数
+	Context    []string              // 上下文内容
+	Matches    []search.ContextMatch // 每个匹配行的行号、匹配位置、内容和上下文
 }
 
 type SearchConfig struct {
 	StartDir     string
 	MaxDepth     int
 	Concurrent   bool
-	MaxWorkers   int
-	IncludeDir   bool
+	MaxWorkers   int // 并发协程数，同时作用于遍历和读取，0 表示按硬件自动选择
+	WalkWorkers  int // 目录遍历协程数，0 表示使用 MaxWorkers 或自动选择
+	ReadWorkers  int // 读取文件（内容搜索、索引）协程数，0 表示使用 MaxWorkers 或自动选择
 	SizeLimit    int64
 	FileTypes    []string
-	ExcludeDirs  []string
+	EntryTypes   []string // 文件类型过滤：f, d, l, s, p, b, c，为空时保留除目录外的所有条目
+	MimeTypes    []string // MIME 类型过滤，支持 text/* 通配
+	ExcludeDirs  []string // 排除模式：名称、glob 或锚定路径
+	IncludeGlobs []string // 包含模式：指定后仅保留匹配的文件
 	GlobalSearch bool
+	HiddenMode   string // 隐藏文件策略：default, include, exclude, only
+	FollowLinks  bool   // 是否跟随符号链接
+	// 文件系统边界配置
+	SameFilesystem bool     // 不进入与起始目录不同的文件系统（-xdev）
+	AllFilesystems bool     // 不跳过伪文件系统和远程文件系统
+	IncludeFSTypes []string // 显式包含的文件系统类型，如 nfs、fuse.sshfs
+	// 忽略文件配置
+	NoIgnore    bool     // 不读取 .gitignore/.ignore/.finderignore
+	IgnoreFiles []string // 额外的 gitignore 格式忽略文件
 	// 新增内容搜索相关配置
 	ContentSearch  bool   // 是否启用内容搜索
 	SearchMode     string // 搜索模式：filename, content, both
 	ContextLines   int    // 上下文行数
+	ContextBefore  int    // 匹配行之前的上下文行数（-B），-1 表示使用 ContextLines
+	ContextAfter   int    // 匹配行之后的上下文行数（-A），-1 表示使用 ContextLines
 	MaxContentSize int64  // 最大内容搜索文件大小
 	CaseSensitive  bool   // 是否区分大小写
+	MaxResults     int    // 最多返回的结果数，达到后停止遍历，0 表示不限制
+	// 当前用户实际访问权限过滤
+	Readable   bool // 仅保留当前进程可读的文件
+	Writable   bool // 仅保留当前进程可写的文件
+	Executable bool // 仅保留当前进程可执行的文件
+	// 断点续搜，为 nil 时不记录进度
+	Checkpoint *Checkpoint
+	// 流式输出：设置后查找函数找到结果时立即回调（可能被多个协程并发调用），
+	// 不再在返回值中累积结果
+	OnResult func(FileInfo)
+	// 读取限速配置（-nice）
+	Nice            bool        // 低影响模式：默认限速并根据读取延迟减少并发
+	ReadBytesPerSec int64       // 每秒最多读取的文件字节数，0 表示不限制
+	ReadFilesPerSec int         // 每秒最多打开读取的文件数（内容搜索、MIME 检测、获取结果信息），0 表示不限制
+	Throttle        *IOThrottle // 读取限速器，为 nil 时不限速也不统计
 	// 新增输出配置
 	OutputPath   string // 输出文件路径
 	OutputFormat string // 输出格式：txt, json, csv
+
+	// 编译后的排除/包含匹配器，首次使用时根据 ExcludeDirs/IncludeGlobs 生成
+	matcherOnce    sync.Once
+	excludeMatcher *PathMatcher
+	includeMatcher *PathMatcher
+
+	// 自动选择的协程数，首次使用时根据 CPU 数和起始目录所在的存储类型确定
+	workersOnce sync.Once
+	autoWalk    int
+	autoRead    int
+}
+
+// contextRange 匹配行前后的上下文行数，未指定 -B/-A 时使用 -c
+func (c *SearchConfig) contextRange() (int, int) {
+	before, after := c.ContextBefore, c.ContextAfter
+	if before < 0 {
+		before = c.ContextLines
+	}
+	if after < 0 {
+		after = c.ContextLines
+	}
+	if before < 0 {
+		before = 0
+	}
+	if after < 0 {
+		after = 0
+	}
+	return before, after
+}
+
+// walkWorkers 目录遍历使用的协程数
+// 优先级：-walk-workers > -w > 自动选择，关闭并发时为 1
+func (c *SearchConfig) walkWorkers() int {
+	switch {
+	case !c.Concurrent:
+		return 1
+	case c.WalkWorkers > 0:
+		return c.WalkWorkers
+	case c.MaxWorkers > 0:
+		return c.MaxWorkers
+	}
+	walk, _ := c.autoWorkers()
+	return walk
+}
+
+// readWorkers 处理文件（读取内容、获取信息）使用的协程数
+// 优先级：-read-workers > -w > 自动选择，关闭并发时为 1
+func (c *SearchConfig) readWorkers() int {
+	switch {
+	case !c.Concurrent:
+		return 1
+	case c.ReadWorkers > 0:
+		return c.ReadWorkers
+	case c.MaxWorkers > 0:
+		return c.MaxWorkers
+	}
+	_, read := c.autoWorkers()
+	return read
+}
+
+// autoWorkers 根据 CPU 数和存储类型选择遍历和读取的协程数
+// 机械硬盘上并发读取会导致频繁寻道，使用较少的协程；SSD 及无法判断的存储按 CPU 数扩展
+func (c *SearchConfig) autoWorkers() (int, int) {
+	c.workersOnce.Do(func() {
+		cpus := runtime.NumCPU()
+		rotational, known := isRotational(c.StartDir)
+		storage := "SSD"
+		switch {
+		case known && rotational:
+			storage = "HDD"
+			c.autoWalk = clampInt(cpus, 2, 8)
+			c.autoRead = 2
+		default:
+			if !known {
+				storage = "未知"
+			}
+			c.autoWalk = clampInt(2*cpus, 4, 32)
+			c.autoRead = clampInt(cpus, 2, 16)
+		}
+		utils.Logger.Printf("[配置] CPU %d 核，存储类型 %s，遍历协程 %d，读取协程 %d",
+			cpus, storage, c.autoWalk, c.autoRead)
+	})
+	return c.autoWalk, c.autoRead
+}
+
+// clampInt 将 n 限制在 [min, max] 范围内
+func clampInt(n, min, max int) int {
+	if n < min {
+		return min
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+// pathMatchers 返回编译后的排除和包含匹配器
+func (c *SearchConfig) pathMatchers() (*PathMatcher, *PathMatcher) {
+	c.matcherOnce.Do(func() {
+		c.excludeMatcher = NewPathMatcher(c.ExcludeDirs)
+		c.includeMatcher = NewPathMatcher(c.IncludeGlobs)
+	})
+	return c.excludeMatcher, c.includeMatcher
 }
 
 func NewDefaultConfig() *SearchConfig {
@@ -39,16 +184,22 @@ func NewDefaultConfig() *SearchConfig {
 		StartDir:     ".",
 		MaxDepth:     -1,
 		Concurrent:   true,
-		MaxWorkers:   5,
-		IncludeDir:   false,
+		MaxWorkers:   0, // 按 CPU 数和存储类型自动选择
 		SizeLimit:    -1,
 		FileTypes:    []string{},
+		EntryTypes:   []string{},
+		MimeTypes:    []string{},
 		ExcludeDirs:  []string{".git", "node_modules"},
+		IncludeGlobs: []string{},
 		GlobalSearch: false,
+		HiddenMode:   HiddenDefault,
+		NoIgnore:     false,
 		// 内容搜索默认配置
 		ContentSearch:  false,
 		SearchMode:     "filename",       // 默认只搜索文件名
 		ContextLines:   2,                // 默认显示2行上下文
+		ContextBefore:  -1,               // 默认同 ContextLines
+		ContextAfter:   -1,               // 默认同 ContextLines
 		MaxContentSize: 10 * 1024 * 1024, // 默认最大10MB文件进行内容搜索
 		CaseSensitive:  false,            // 默认不区分大小写
 	}
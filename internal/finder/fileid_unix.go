@@ -0,0 +1,32 @@
+//go:build !windows
+
+package finder
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileKey 唯一标识文件系统中的一个目录
+type fileKey struct {
+	dev uint64
+	ino uint64
+}
+
+// fileKeyOf 使用设备号和 inode 标识目录
+func fileKeyOf(path string, info os.FileInfo) (fileKey, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileKey{}, false
+	}
+	return fileKey{dev: uint64(st.Dev), ino: uint64(st.Ino)}, true
+}
+
+// deviceOf 返回文件所在文件系统的设备号
+func deviceOf(info os.FileInfo) (uint64, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(st.Dev), true
+}
@@ -0,0 +1,30 @@
+package finder
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// fileKey 唯一标识文件系统中的一个目录
+// Windows 的 FileInfo 不包含 inode，使用解析符号链接后的真实路径代替
+type fileKey struct {
+	path string
+}
+
+// fileKeyOf 使用解析后的真实路径标识目录
+func fileKeyOf(path string, info os.FileInfo) (fileKey, bool) {
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fileKey{}, false
+	}
+	abs, err := filepath.Abs(real)
+	if err != nil {
+		return fileKey{}, false
+	}
+	return fileKey{path: abs}, true
+}
+
+// deviceOf Windows 的 FileInfo 不包含设备号，不支持跨文件系统检测
+func deviceOf(info os.FileInfo) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,64 @@
+package finder
+
+import (
+	"file-finder/internal/utils"
+	"path"
+	"strings"
+)
+
+// mountEntry 挂载表中的一项
+type mountEntry struct {
+	dev    uint64 // 文件系统的设备号
+	fsType string // 文件系统类型
+}
+
+// skippedFSTypes 默认跳过的伪文件系统和远程文件系统，支持 glob
+var skippedFSTypes = []string{
+	// 伪文件系统
+	"proc", "sysfs", "cgroup", "cgroup2", "devtmpfs", "devpts", "debugfs",
+	"tracefs", "securityfs", "pstore", "bpf", "configfs", "fusectl", "mqueue",
+	"hugetlbfs", "autofs", "binfmt_misc", "efivarfs", "selinuxfs", "nsfs",
+	"rpc_pipefs", "nfsd",
+	// 远程文件系统
+	"nfs", "nfs4", "cifs", "smb3", "smbfs", "ncpfs", "afs", "ceph", "glusterfs",
+	"fuse.*",
+}
+
+// ParseFSTypes 解析 -include-fs 参数
+func ParseFSTypes(value string) []string {
+	var types []string
+	for _, t := range strings.Split(value, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// matchFSType 检查文件系统类型是否匹配任一模式
+func matchFSType(fsType string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, fsType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// skippedDevices 读取挂载表，返回需要跳过的文件系统设备号及其类型
+// 通过 -include-fs 显式包含的类型不会被跳过
+func skippedDevices(config *SearchConfig) map[uint64]string {
+	mounts, err := readMounts()
+	if err != nil {
+		utils.Logger.Printf("[遍历] 读取挂载表失败: %v", err)
+		return nil
+	}
+
+	devices := make(map[uint64]string)
+	for _, m := range mounts {
+		if matchFSType(m.fsType, skippedFSTypes) && !matchFSType(m.fsType, config.IncludeFSTypes) {
+			devices[m.dev] = m.fsType
+		}
+	}
+	return devices
+}
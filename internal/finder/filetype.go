@@ -0,0 +1,87 @@
+package finder
+
+import (
+	"file-finder/internal/parser"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// 文件类型代码，与 find(1) 的 -type 保持一致
+const (
+	TypeFile    = "f" // 普通文件
+	TypeDir     = "d" // 目录
+	TypeSymlink = "l" // 符号链接
+	TypeSocket  = "s" // 套接字
+	TypeFIFO    = "p" // 命名管道
+	TypeBlock   = "b" // 块设备
+	TypeChar    = "c" // 字符设备
+)
+
+// EntryTypeOf 根据文件模式返回类型代码
+func EntryTypeOf(mode os.FileMode) string {
+	switch {
+	case mode.IsRegular():
+		return TypeFile
+	case mode.IsDir():
+		return TypeDir
+	case mode&os.ModeSymlink != 0:
+		return TypeSymlink
+	case mode&os.ModeSocket != 0:
+		return TypeSocket
+	case mode&os.ModeNamedPipe != 0:
+		return TypeFIFO
+	case mode&os.ModeDevice != 0 && mode&os.ModeCharDevice != 0:
+		return TypeChar
+	case mode&os.ModeDevice != 0:
+		return TypeBlock
+	}
+	// 其他特殊类型（如 Windows 上的 irregular）按普通文件处理
+	return TypeFile
+}
+
+// ParseEntryTypes 解析 -type 参数，如 "f,d,l"
+func ParseEntryTypes(value string) ([]string, error) {
+	var types []string
+	for _, t := range strings.Split(value, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		switch t {
+		case TypeFile, TypeDir, TypeSymlink, TypeSocket, TypeFIFO, TypeBlock, TypeChar:
+			types = append(types, t)
+		default:
+			return nil, fmt.Errorf("不支持的文件类型: %s (可选: f,d,l,s,p,b,c)", t)
+		}
+	}
+	return types, nil
+}
+
+// matchEntryType 检查文件类型是否满足 -type 过滤条件
+// 未指定 -type 时保留除目录外的所有条目，指定 d 时才包含目录
+func matchEntryType(mode os.FileMode, config *SearchConfig) bool {
+	if len(config.EntryTypes) == 0 {
+		return !mode.IsDir()
+	}
+	entryType := EntryTypeOf(mode)
+	for _, t := range config.EntryTypes {
+		if t == entryType {
+			return true
+		}
+	}
+	return false
+}
+
+// matchMIMEFilter 检查文件内容类型是否满足 -mime 过滤条件
+// 仅在指定了 -mime 时才读取文件头，info 为遍历器已获取的文件信息（-follow 时为链接目标的信息）
+func matchMIMEFilter(path string, info os.FileInfo, config *SearchConfig) bool {
+	if len(config.MimeTypes) == 0 {
+		return true
+	}
+	mime, err := parser.DetectMIME(path, info)
+	if err != nil {
+		return false
+	}
+	return parser.MatchMIME(mime, config.MimeTypes)
+}
@@ -2,109 +2,89 @@ package finder
 
 import (
 	"bytes"
+	"context"
+	"file-finder/internal/parser"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 	"unicode/utf8"
 
 	"golang.org/x/text/encoding/simplifiedchinese"
 	"golang.org/x/text/transform"
 )
 
-func FindFilesWithFlag(pattern string, config *SearchConfig) (map[string]FileInfo, error) {
-	results := sync.Map{}
+// FindFilesWithFlag 遍历目录查找文件名包含 pattern 的文件
+// 设置了 config.OnResult 时结果流式输出，返回值为空
+func FindFilesWithFlag(ctx context.Context, pattern string, config *SearchConfig) (map[string]FileInfo, error) {
+	results := newResultCollector(config.OnResult)
 
-	if config.Concurrent {
-		return findFilesWithFlagConcurrent(pattern, config)
-	}
-
-	err := filepath.Walk(config.StartDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			if os.IsPermission(err) {
-				return filepath.SkipDir
-			}
-			return nil
-		}
-
-		if shouldSkipFile(path, info, config) {
-			if info.IsDir() {
-				return filepath.SkipDir
-			}
-			return nil
-		}
-
-		if !info.IsDir() && strings.Contains(strings.ToLower(info.Name()), strings.ToLower(pattern)) {
-			fileInfo, err := GetFileInfo(path, info, config)
-			if err != nil {
-				return nil
-			}
-			results.Store(path, fileInfo)
-		}
-		return nil
-	})
+	ctx, limit, stop := newResultLimit(ctx, config)
+	defer stop()
 
-	return syncMapToMap(results), err
-}
+	entries, err := NewWalker(config).Walk(ctx, config.StartDir)
+	if err != nil {
+		return nil, err
+	}
 
-func findFilesWithFlagConcurrent(pattern string, config *SearchConfig) (map[string]FileInfo, error) {
-	results := sync.Map{}
-	paths := make(chan string, 100)
+	lowerPattern := strings.ToLower(pattern)
 	var wg sync.WaitGroup
-
-	for i := 0; i < config.MaxWorkers; i++ {
+	for i := 0; i < config.readWorkers(); i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for path := range paths {
-				info, err := os.Stat(path)
-				if err != nil {
+			for entry := range entries {
+				if ctx.Err() != nil {
+					continue
+				}
+				if !matchEntryType(entry.Type(), config) ||
+					!strings.Contains(strings.ToLower(entry.Name()), lowerPattern) {
 					continue
 				}
 
-				if strings.Contains(strings.ToLower(info.Name()), strings.ToLower(pattern)) {
-					fileInfo, err := GetFileInfo(path, info, config)
-					if err != nil {
-						continue
-					}
-					results.Store(path, fileInfo)
+				info, err := entry.Info()
+				if err != nil || !matchMIMEFilter(entry.Path, info, config) {
+					continue
 				}
+				fileInfo, err := GetFileInfo(entry.Path, info, config)
+				if err != nil || !limit.take() {
+					continue
+				}
+				results.add(fileInfo)
 			}
 		}()
 	}
-
-	err := filepath.Walk(config.StartDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			if os.IsPermission(err) {
-				return filepath.SkipDir
-			}
-			return nil
-		}
-
-		if shouldSkipFile(path, info, config) {
-			if info.IsDir() {
-				return filepath.SkipDir
-			}
-			return nil
-		}
-
-		if !info.IsDir() {
-			paths <- path
-		}
-		return nil
-	})
-
-	close(paths)
 	wg.Wait()
 
-	return syncMapToMap(results), err
+	return results.collected(), nil
 }
 
+// GetFileInfo 获取结果的详细信息，普通文件会读取内容并检测 MIME 类型
+// 读取受 -read-bps/-read-fps 限速，结果已确定，等待配额时不因取消而放弃
 func GetFileInfo(path string, info os.FileInfo, config *SearchConfig) (FileInfo, error) {
+	if info.Mode().IsRegular() {
+		config.Throttle.acquire(context.Background())
+		start := time.Now()
+		fileInfo, err := getFileInfo(path, info, config)
+		read := info.Size()
+		if config.SizeLimit != -1 && read > config.SizeLimit {
+			read = 0 // 超过大小限制时只读取文件头检测 MIME 类型
+		}
+		config.Throttle.release(read, time.Since(start))
+		return fileInfo, err
+	}
+	return getFileInfo(path, info, config)
+}
+
+// getFileInfo 读取内容并检测 MIME 类型，不限速
+func getFileInfo(path string, info os.FileInfo, config *SearchConfig) (FileInfo, error) {
 	content := ""
-	if config.SizeLimit == -1 || info.Size() <= config.SizeLimit {
+	// 仅读取普通文件的内容，避免在管道、设备等特殊文件上阻塞
+	if info.Mode().IsRegular() && (config.SizeLimit == -1 || info.Size() <= config.SizeLimit) {
 		data, err := os.ReadFile(path)
 		if err != nil {
 			return FileInfo{}, err
@@ -123,15 +103,34 @@ func GetFileInfo(path string, info os.FileInfo, config *SearchConfig) (FileInfo,
 		}
 	}
 
+	linkTarget, linkBroken := linkTargetOf(path, info)
+	mime := ""
+	if !linkBroken {
+		mime = detectMIME(path, info)
+	}
+
 	return FileInfo{
 		Path:        path,
 		Size:        info.Size(),
 		ModTime:     info.ModTime().Format("2006-01-02 15:04:05"),
 		Permissions: info.Mode().String(),
+		Type:        EntryTypeOf(info.Mode()),
+		MimeType:    mime,
+		LinkTarget:  linkTarget,
+		LinkBroken:  linkBroken,
 		Content:     content,
 	}, nil
 }
 
+// detectMIME 根据已获取的文件信息检测文件的 MIME 类型，失败时返回空字符串
+func detectMIME(path string, info os.FileInfo) string {
+	mime, err := parser.DetectMIME(path, info)
+	if err != nil {
+		return ""
+	}
+	return mime
+}
+
 // 检查是否是二进制文件
 func isBinary(data []byte) bool {
 	if len(data) > 1024 {
@@ -174,20 +173,32 @@ func min(a, b int) int {
 	return b
 }
 
-func shouldSkipFile(path string, info os.FileInfo, config *SearchConfig) bool {
-	// 检查排除目录
-	for _, excludeDir := range config.ExcludeDirs {
-		if strings.Contains(path, excludeDir) {
-			return true
-		}
+// shouldSkipFile 检查条目是否被排除/包含模式、隐藏文件策略、扩展名或深度限制过滤
+// 返回 true 的目录不会继续向下遍历
+func shouldSkipFile(path string, entry fs.DirEntry, config *SearchConfig) bool {
+	exclude, include := config.pathMatchers()
+
+	// 检查排除模式
+	if exclude.Match(config.StartDir, path) {
+		return true
+	}
+
+	// 检查隐藏文件策略
+	if shouldSkipHidden(path, entry, config) {
+		return true
+	}
+
+	// 检查包含模式，目录不受限制以便继续向下遍历
+	if !include.Empty() && !entry.IsDir() && !include.Match(config.StartDir, path) {
+		return true
 	}
 
 	// 检查文件类型
-	if len(config.FileTypes) > 0 && !info.IsDir() {
+	if len(config.FileTypes) > 0 && !entry.IsDir() {
 		ext := strings.ToLower(filepath.Ext(path))
 		found := false
 		for _, allowedType := range config.FileTypes {
-			if ext == "."+allowedType {
+			if ext == "."+strings.ToLower(allowedType) {
 				found = true
 				break
 			}
@@ -197,22 +208,10 @@ func shouldSkipFile(path string, info os.FileInfo, config *SearchConfig) bool {
 		}
 	}
 
-	// 检查最大深度
-	if config.MaxDepth > 0 {
-		depth := strings.Count(path, string(os.PathSeparator))
-		if depth > config.MaxDepth {
-			return true
-		}
+	// 检查最大深度（相对起始目录）
+	if config.MaxDepth > 0 && len(relPathSegments(config.StartDir, path)) > config.MaxDepth {
+		return true
 	}
 
 	return false
 }
-
-func syncMapToMap(syncMap sync.Map) map[string]FileInfo {
-	result := make(map[string]FileInfo)
-	syncMap.Range(func(key, value interface{}) bool {
-		result[key.(string)] = value.(FileInfo)
-		return true
-	})
-	return result
-}
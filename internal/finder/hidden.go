@@ -0,0 +1,77 @@
+package finder
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// 隐藏文件策略
+const (
+	HiddenDefault = "default" // 遍历隐藏文件，但跳过常见的噪声目录
+	HiddenInclude = "include" // 包含所有隐藏文件和目录
+	HiddenExclude = "exclude" // 排除所有隐藏文件和目录
+	HiddenOnly    = "only"    // 只保留隐藏文件或位于隐藏目录中的文件
+)
+
+// noisyHiddenDirs 默认策略下跳过的隐藏目录，多为缓存、包管理器和回收站
+// 含 "/" 的模式会锚定到起始目录，需加 "**/" 才能在 -g、-d /home 等任意深度匹配
+var noisyHiddenDirs = NewPathMatcher([]string{
+	".cache", ".npm", ".yarn", ".pnpm-store", ".gradle", ".m2",
+	".cargo", ".rustup", ".nvm", ".pyenv", ".rbenv", ".conda",
+	".vscode-server", ".cursor-server", ".docker", "**/.local/share/Trash",
+	".Trash", ".Trash-*", ".Spotlight-V100", ".fseventsd", ".DocumentRevisions-V100",
+})
+
+// ParseHiddenMode 校验 -hidden 参数
+func ParseHiddenMode(value string) (string, error) {
+	switch value {
+	case "", HiddenDefault:
+		return HiddenDefault, nil
+	case HiddenInclude, HiddenExclude, HiddenOnly:
+		return value, nil
+	}
+	return "", fmt.Errorf("不支持的隐藏文件策略: %s (可选: include/exclude/only)", value)
+}
+
+// isHiddenName 以 "." 开头的名称视为隐藏
+func isHiddenName(name string) bool {
+	return len(name) > 1 && name[0] == '.' && name != ".."
+}
+
+// shouldSkipHidden 按隐藏文件策略判断是否跳过
+// 除条目自身外还会检查其相对起始目录的每一级父目录，保证索引搜索与遍历结果一致
+func shouldSkipHidden(path string, entry fs.DirEntry, config *SearchConfig) bool {
+	mode := config.HiddenMode
+	if mode == HiddenInclude {
+		return false
+	}
+	if mode != HiddenExclude && noisyHiddenDirs.Match(config.StartDir, path) {
+		return true
+	}
+	switch mode {
+	case HiddenExclude:
+		return isHiddenEntry(path, entry, config)
+	case HiddenOnly:
+		// only 模式下目录需要继续向下遍历以找到其中的隐藏文件，是否输出由 hiddenOnlyRejects 判断
+		return !entry.IsDir() && !isHiddenEntry(path, entry, config)
+	}
+	return false
+}
+
+// hiddenOnlyRejects -hidden only 时不是隐藏条目的目录只用于向下遍历，不作为结果输出
+func hiddenOnlyRejects(path string, entry fs.DirEntry, config *SearchConfig) bool {
+	return config.HiddenMode == HiddenOnly && entry.IsDir() && !isHiddenEntry(path, entry, config)
+}
+
+// isHiddenEntry 条目自身带有隐藏属性，或其相对起始目录的任一级路径以 "." 开头
+func isHiddenEntry(path string, entry fs.DirEntry, config *SearchConfig) bool {
+	if isHiddenAttr(entry) {
+		return true
+	}
+	for _, seg := range relPathSegments(config.StartDir, path) {
+		if isHiddenName(seg) {
+			return true
+		}
+	}
+	return false
+}
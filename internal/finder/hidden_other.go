@@ -0,0 +1,10 @@
+//go:build !windows
+
+package finder
+
+import "io/fs"
+
+// isHiddenAttr 非 Windows 系统没有隐藏属性，仅依据名称判断
+func isHiddenAttr(entry fs.DirEntry) bool {
+	return false
+}
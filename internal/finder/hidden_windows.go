@@ -0,0 +1,19 @@
+package finder
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// isHiddenAttr 检查 Windows 的隐藏文件属性
+// Windows 上目录项信息来自 FindNextFile，获取 Info 不需要额外的系统调用
+func isHiddenAttr(entry fs.DirEntry) bool {
+	info, err := entry.Info()
+	if err != nil {
+		return false
+	}
+	if attr, ok := info.Sys().(*syscall.Win32FileAttributeData); ok {
+		return attr.FileAttributes&syscall.FILE_ATTRIBUTE_HIDDEN != 0
+	}
+	return false
+}
@@ -0,0 +1,206 @@
+package finder
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ignoreFileNames 每个目录下会读取的忽略文件，后者优先级更高
+var ignoreFileNames = []string{".gitignore", ".ignore", ".finderignore"}
+
+// ignoreRule 单条 gitignore 规则
+type ignoreRule struct {
+	pattern  string // 去掉前导 "/" 和末尾 "/" 后的模式
+	negate   bool   // 以 "!" 开头的反向规则
+	dirOnly  bool   // 以 "/" 结尾，只匹配目录
+	anchored bool   // 包含 "/"，相对于忽略文件所在目录匹配
+	base     string // 忽略文件所在目录
+}
+
+// IgnoreMatcher 按 gitignore 语义判断路径是否应被忽略
+// 各目录下的忽略文件在遍历到该目录时按需加载并缓存
+type IgnoreMatcher struct {
+	root   string
+	global []ignoreRule // 通过 -ignore-file 指定的规则，相对于起始目录
+	mu     sync.Mutex
+	cache  map[string][]ignoreRule
+}
+
+// NewIgnoreMatcher 创建忽略规则匹配器，指定 -no-ignore 时返回 nil
+func NewIgnoreMatcher(root string, config *SearchConfig) *IgnoreMatcher {
+	if config.NoIgnore {
+		return nil
+	}
+
+	root = filepath.Clean(root)
+	m := &IgnoreMatcher{
+		root:  root,
+		cache: make(map[string][]ignoreRule),
+	}
+	for _, file := range config.IgnoreFiles {
+		rules, err := parseIgnoreFile(file, root)
+		if err != nil {
+			logAndPrint("[忽略] 读取忽略文件 %s 失败: %v", file, err)
+			continue
+		}
+		m.global = append(m.global, rules...)
+	}
+	return m
+}
+
+// Match 判断路径是否被忽略
+// 规则按 自定义忽略文件 -> 起始目录 -> 更深层目录 的顺序生效，最后匹配的规则决定结果
+func (m *IgnoreMatcher) Match(filePath string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+
+	filePath = filepath.Clean(filePath)
+	rel, err := filepath.Rel(m.root, filePath)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+
+	ignored := false
+	apply := func(rules []ignoreRule) {
+		for _, rule := range rules {
+			if rule.match(filePath, isDir) {
+				ignored = !rule.negate
+			}
+		}
+	}
+
+	apply(m.global)
+
+	// 依次加载从起始目录到父目录的每一级忽略文件
+	dir := m.root
+	segments := strings.Split(filepath.ToSlash(rel), "/")
+	for i := 0; i < len(segments); i++ {
+		apply(m.rulesFor(dir))
+		dir = filepath.Join(dir, segments[i])
+	}
+
+	return ignored
+}
+
+// rulesFor 获取目录下忽略文件中的规则
+func (m *IgnoreMatcher) rulesFor(dir string) []ignoreRule {
+	m.mu.Lock()
+	rules, ok := m.cache[dir]
+	m.mu.Unlock()
+	if ok {
+		return rules
+	}
+
+	for _, name := range ignoreFileNames {
+		fileRules, err := parseIgnoreFile(filepath.Join(dir, name), dir)
+		if err != nil {
+			continue
+		}
+		rules = append(rules, fileRules...)
+	}
+
+	m.mu.Lock()
+	m.cache[dir] = rules
+	m.mu.Unlock()
+	return rules
+}
+
+// parseIgnoreFile 解析 gitignore 格式的忽略文件
+func parseIgnoreFile(file, base string) ([]ignoreRule, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreLine(scanner.Text(), base); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, scanner.Err()
+}
+
+// parseIgnoreLine 解析单行规则，空行和注释返回 false
+func parseIgnoreLine(line, base string) (ignoreRule, bool) {
+	line = strings.TrimSuffix(line, "\r")
+	// 去掉未转义的尾部空格
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	rule.pattern = line
+	return rule, true
+}
+
+// match 判断规则是否匹配路径
+func (r ignoreRule) match(filePath string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+
+	rel, err := filepath.Rel(r.base, filePath)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+
+	if !r.anchored {
+		// 不含 "/" 的模式匹配任意层级的文件名
+		ok, _ := path.Match(r.pattern, path.Base(rel))
+		return ok
+	}
+	return matchGlobSegments(strings.Split(r.pattern, "/"), strings.Split(rel, "/"))
+}
+
+// matchGlobSegments 按路径分段匹配 glob 模式，"**" 可匹配零个或多个目录
+func matchGlobSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchGlobSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+	return matchGlobSegments(pattern[1:], segments[1:])
+}
@@ -0,0 +1,105 @@
+package finder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFile 创建文件及其所在目录
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIgnoreMatcher(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".gitignore"), "# 注释\n*.log\n!keep.log\nbuild/\n/top.txt\ndocs/**/*.tmp\n")
+	writeFile(t, filepath.Join(root, "sub", ".gitignore"), "!*.log\nlocal.txt\n")
+	writeFile(t, filepath.Join(root, "sub", ".finderignore"), "debug.log\n")
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"app.log", false, true},
+		{"keep.log", false, false},
+		{"a/b/app.log", false, true},
+		{"a/b/keep.log", false, false},
+		{"build", true, true},
+		{"build", false, false}, // 以 / 结尾的规则只匹配目录
+		{"top.txt", false, true},
+		{"a/top.txt", false, false}, // 以 / 开头的规则锚定在忽略文件所在目录
+		{"docs/x/y/a.tmp", false, true},
+		{"docs/a.tmp", false, true},
+		{"other/a.tmp", false, false},
+		{"sub/app.log", false, false},  // 子目录的反向规则覆盖上级规则
+		{"sub/debug.log", false, true}, // .finderignore 优先于同目录的 .gitignore
+		{"sub/local.txt", false, true},
+		{"local.txt", false, false}, // 子目录的规则不作用于上级目录
+	}
+	m := NewIgnoreMatcher(root, NewDefaultConfig())
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := m.Match(filepath.Join(root, filepath.FromSlash(tt.path)), tt.isDir); got != tt.want {
+				t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIgnoreMatcherIgnoreFileAndNoIgnore(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".gitignore"), "*.log\n")
+	extra := filepath.Join(t.TempDir(), "extra.ignore")
+	writeFile(t, extra, "*.bak\n")
+
+	config := NewDefaultConfig()
+	config.IgnoreFiles = []string{extra}
+	m := NewIgnoreMatcher(root, config)
+	if !m.Match(filepath.Join(root, "a.bak"), false) {
+		t.Error("-ignore-file 中的规则未生效")
+	}
+	if !m.Match(filepath.Join(root, "a.log"), false) {
+		t.Error("指定 -ignore-file 时 .gitignore 中的规则未生效")
+	}
+	if m.Match(root, true) {
+		t.Error("起始目录本身不应被忽略")
+	}
+
+	config.NoIgnore = true
+	if m := NewIgnoreMatcher(root, config); m != nil || m.Match(filepath.Join(root, "a.log"), false) {
+		t.Error("-no-ignore 时不应忽略任何路径")
+	}
+}
+
+func TestParseIgnoreLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want ignoreRule
+		ok   bool
+	}{
+		{"", ignoreRule{}, false},
+		{"# comment", ignoreRule{}, false},
+		{"*.log   ", ignoreRule{pattern: "*.log"}, true},
+		{`\#file`, ignoreRule{pattern: "#file"}, true},
+		{`\!file`, ignoreRule{pattern: "!file"}, true},
+		{"!keep", ignoreRule{pattern: "keep", negate: true}, true},
+		{"out/", ignoreRule{pattern: "out", dirOnly: true}, true},
+		{"/root.txt", ignoreRule{pattern: "root.txt", anchored: true}, true},
+		{"a/b\r", ignoreRule{pattern: "a/b", anchored: true}, true},
+		{"/", ignoreRule{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseIgnoreLine(tt.line, "")
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseIgnoreLine(%q) = %+v, %v, want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
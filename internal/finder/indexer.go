@@ -1,11 +1,13 @@
 package finder
 
 import (
+	"context"
 	"file-finder/internal/utils"
+	"io/fs"
 	"os"
-	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -15,14 +17,42 @@ type FileIndex struct {
 	Size        int64
 	ModTime     time.Time
 	IsDir       bool
+	Type        string // 文件类型代码：f, d, l, s, p, b, c
 	Permissions os.FileMode
 }
 
-type Indexer struct {
-	mu          sync.RWMutex
+// IndexStats 索引构建统计
+type IndexStats struct {
+	Indexed int64 // 已加入索引的条目数
+	Skipped int64 // 被忽略规则、排除模式等过滤掉的条目数
+	Errors  int64 // 无法读取的目录和无法获取信息的条目数
+}
+
+// indexSnapshot 索引快照，发布后 fileIndices 和 nameIndices 不再修改，可被任意多个搜索无锁并发读取
+// 搜索时发现的过期条目写入 overlay，查找时优先于 fileIndices，下次重建时随快照一起丢弃
+type indexSnapshot struct {
 	fileIndices map[string]FileIndex
 	nameIndices map[string][]string
+	overlay     sync.Map // 路径 -> 刷新后的 FileIndex
 	lastUpdate  time.Time
+	stats       IndexStats
+	generation  uint64 // 每次重建递增
+}
+
+// lookup 返回路径的索引条目，刷新过的条目优先
+func (s *indexSnapshot) lookup(path string) (FileIndex, bool) {
+	if v, ok := s.overlay.Load(path); ok {
+		return v.(FileIndex), true
+	}
+	file, ok := s.fileIndices[path]
+	return file, ok
+}
+
+// Indexer 文件名索引
+// 读取通过原子加载当前快照完成，不加锁；重建时生成新快照并原子替换，刷新过期条目只写入当前快照的 overlay
+type Indexer struct {
+	mu       sync.Mutex // 串行化快照的写入方
+	snapshot atomic.Pointer[indexSnapshot]
 }
 
 var (
@@ -32,199 +62,212 @@ var (
 
 func GetIndexer() *Indexer {
 	once.Do(func() {
-		defaultIndexer = &Indexer{
+		defaultIndexer = &Indexer{}
+		defaultIndexer.snapshot.Store(&indexSnapshot{
 			fileIndices: make(map[string]FileIndex),
 			nameIndices: make(map[string][]string),
-		}
+		})
 	})
 	return defaultIndexer
 }
 
-func (idx *Indexer) BuildIndex(startDir string, config *SearchConfig) error {
+// BuildIndex 遍历 startDir 重建文件索引，ctx 取消时保留已索引的部分
+// 工作协程直接消费遍历器的条目通道，处理不过来时遍历器阻塞等待，不会丢弃任何条目
+func (idx *Indexer) BuildIndex(ctx context.Context, startDir string, config *SearchConfig) error {
 	// 创建临时映射以存储结果
 	tempFileIndices := make(map[string]FileIndex)
 	tempNameIndices := make(map[string][]string)
+	var tempMu sync.Mutex
+
+	walker := NewWalker(config)
+	entries, err := walker.Walk(ctx, startDir)
+	if err != nil {
+		return err
+	}
 
 	// 创建进度条
 	progress := utils.NewProgressBar(50)
 	progress.Start()
 
-	// 使用适度的缓冲区大小
-	filesChan := make(chan FileIndex, 5000)
-	errChan := make(chan error, 1)
+	var indexed, statErrors int64
 	var wg sync.WaitGroup
 
-	// 使用固定的工作协程数
-	const workerCount = 4 // 使用固定的4个工作协程
-	for i := 0; i < workerCount; i++ {
+	// 工作协程数与其他查找函数一致，由 -w/-read-workers 或自动选择决定
+	for i := 0; i < config.readWorkers(); i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			// 每个工作协程使用本地缓存
-			localCache := make(map[string]FileIndex, 1000)
-
-			for file := range filesChan {
-				localCache[file.Path] = file
-
-				// 当本地缓存达到一定大小时批量提交
-				if len(localCache) >= 1000 {
-					idx.mu.Lock()
-					for k, v := range localCache {
-						tempFileIndices[k] = v
-						tempNameIndices[v.Name] = append(tempNameIndices[v.Name], v.Path)
-					}
-					idx.mu.Unlock()
-
-					// 清空本地缓存
-					localCache = make(map[string]FileIndex, 1000)
+			// 每个工作协程使用本地缓存，批量提交以减少锁竞争
+			localCache := make([]FileIndex, 0, 1000)
+			flush := func() {
+				tempMu.Lock()
+				for _, v := range localCache {
+					tempFileIndices[v.Path] = v
+					tempNameIndices[v.Name] = append(tempNameIndices[v.Name], v.Path)
 				}
-				progress.Increment()
+				tempMu.Unlock()
+				atomic.AddInt64(&indexed, int64(len(localCache)))
+				localCache = localCache[:0]
 			}
 
-			// 提交剩余的本地缓存
-			if len(localCache) > 0 {
-				idx.mu.Lock()
-				for k, v := range localCache {
-					tempFileIndices[k] = v
-					tempNameIndices[v.Name] = append(tempNameIndices[v.Name], v.Path)
+			for entry := range entries {
+				if entry.IsDir() {
+					// 更新当前正在处理的目录
+					progress.SetCurrentDir(entry.Path)
 				}
-				idx.mu.Unlock()
-			}
-		}()
-	}
 
-	// 启动一个协程来遍历文件系统
-	go func() {
-		err := filepath.Walk(startDir, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				if os.IsPermission(err) {
-					return filepath.SkipDir
+				info, err := entry.Info()
+				if err != nil {
+					atomic.AddInt64(&statErrors, 1)
+					utils.Logger.Printf("[索引] 获取文件信息失败: %s: %v", entry.Path, err)
+					continue
 				}
-				return nil
-			}
 
-			if info.IsDir() {
-				// 更新当前正在处理的目录
-				progress.SetCurrentDir(path)
-			}
+				localCache = append(localCache, FileIndex{
+					Path:        entry.Path,
+					Name:        info.Name(),
+					Size:        info.Size(),
+					ModTime:     info.ModTime(),
+					IsDir:       info.IsDir(),
+					Type:        EntryTypeOf(info.Mode()),
+					Permissions: info.Mode(),
+				})
+				progress.Increment()
 
-			if shouldSkipFile(path, info, config) {
-				if info.IsDir() {
-					return filepath.SkipDir
+				// 当本地缓存达到一定大小时批量提交
+				if len(localCache) >= 1000 {
+					flush()
 				}
-				return nil
-			}
-
-			// 使用非阻塞的方式发送文件信息
-			select {
-			case filesChan <- FileIndex{
-				Path: path,
-				Name: info.Name(),
-				Size: info.Size(),
-
-				ModTime:     info.ModTime(),
-				IsDir:       info.IsDir(),
-				Permissions: info.Mode(),
-			}:
-			case <-time.After(10 * time.Millisecond):
-				// 如果通道已满，短暂等待后继续
-				return nil
 			}
 
-			return nil
-		})
-
-		close(filesChan)
-		if err != nil {
-			errChan <- err
-		}
-		close(errChan)
-	}()
+			// 提交剩余的本地缓存
+			flush()
+		}()
+	}
 
 	// 等待所有工作协程完成
 	wg.Wait()
 
-	// 检查是否有错误发生
-	if err := <-errChan; err != nil {
-		progress.Stop(false)
-		return err
+	walkStats := walker.Stats()
+	stats := IndexStats{
+		Indexed: indexed,
+		Skipped: walkStats.Skipped,
+		Errors:  walkStats.Errors + statErrors,
 	}
 
-	// 更新索引
+	// 发布新快照，正在进行的搜索继续使用旧快照
 	idx.mu.Lock()
-	idx.fileIndices = tempFileIndices
-	idx.nameIndices = tempNameIndices
-	idx.lastUpdate = time.Now()
+	idx.snapshot.Store(&indexSnapshot{
+		fileIndices: tempFileIndices,
+		nameIndices: tempNameIndices,
+		lastUpdate:  time.Now(),
+		stats:       stats,
+		generation:  idx.snapshot.Load().generation + 1,
+	})
 	idx.mu.Unlock()
 
-	progress.Stop(true)
+	progress.Stop(ctx.Err() == nil)
+	utils.Logger.Printf("[索引] 已索引 %d 个条目，跳过 %d 个，错误 %d 个", stats.Indexed, stats.Skipped, stats.Errors)
+	if stats.Errors > 0 {
+		utils.PrintWarning("索引时有 %d 个条目无法读取（权限不足或已被删除），使用 -l 查看详情", stats.Errors)
+	}
 	return nil
 }
 
-func (idx *Indexer) addToIndex(file FileIndex) {
-	idx.fileIndices[file.Path] = file
-	idx.nameIndices[file.Name] = append(idx.nameIndices[file.Name], file.Path)
+// Stats 返回最近一次构建索引的统计
+func (idx *Indexer) Stats() IndexStats {
+	return idx.snapshot.Load().stats
 }
 
-func (idx *Indexer) Search(keyword string, config *SearchConfig) (map[string]FileInfo, error) {
-	idx.mu.RLock()
-	defer idx.mu.RUnlock()
+// Empty 索引是否为空
+func (idx *Indexer) Empty() bool {
+	return len(idx.snapshot.Load().fileIndices) == 0
+}
 
-	results := make(map[string]FileInfo)
+// refresh 用最新的文件信息更新过期条目，只写入快照的 overlay，开销与过期条目数成正比
+// 条目名称由路径决定，名称索引不需要更新；更新期间重建的索引优先，不会被旧数据覆盖
+func (idx *Indexer) refresh(base *indexSnapshot, updates []FileIndex) {
+	if len(updates) == 0 || idx.snapshot.Load() != base {
+		// 搜索期间索引已被重建，更新基于的是旧索引，直接丢弃
+		return
+	}
+	for _, file := range updates {
+		base.overlay.Store(file.Path, file)
+	}
+}
+
+// Search 在索引中按文件名搜索，ctx 取消或达到 -max-results 时返回已找到的结果
+// 设置了 config.OnResult 时结果流式输出，返回值为空
+func (idx *Indexer) Search(ctx context.Context, keyword string, config *SearchConfig) (map[string]FileInfo, error) {
+	return idx.search(ctx, keyword, config, config.OnResult)
+}
+
+// search 在索引中按文件名搜索，emit 不为 nil 时结果立即交给 emit
+func (idx *Indexer) search(ctx context.Context, keyword string, config *SearchConfig, emit func(FileInfo)) (map[string]FileInfo, error) {
+	snap := idx.snapshot.Load()
+	var updates []FileIndex
+
+	results := newResultCollector(emit)
+	ctx, limit, stop := newResultLimit(ctx, config)
+	defer stop()
 
 	// 如果索引太旧，建议重建
-	if time.Since(idx.lastUpdate) > 30*time.Minute {
+	if time.Since(snap.lastUpdate) > 30*time.Minute {
 		utils.Logger.Print("[索引] 索引已过期，建议重建")
 	}
 
 	// 使用名称索引快速查找
-	for name, paths := range idx.nameIndices {
+	for name, paths := range snap.nameIndices {
+		if ctx.Err() != nil {
+			break
+		}
 		if !strings.Contains(strings.ToLower(name), strings.ToLower(keyword)) {
 			continue
 		}
 
 		for _, path := range paths {
 			// 使用索引中的信息
-			if fileIndex, ok := idx.fileIndices[path]; ok {
-				// 验证文件是否仍然存在
-				info, err := os.Stat(path)
+			if fileIndex, ok := snap.lookup(path); ok {
+				// 验证文件是否仍然存在（未指定 -follow 时不跟随符号链接，以便识别链接本身的类型）
+				info, err := StatEntry(path, config)
 				if err != nil {
 					continue
 				}
 
-				// 如果是目录且不包括目录，则跳过
-				if info.IsDir() && !config.IncludeDir {
+				// 按 -type 和 -mime 过滤文件类型
+				if !matchEntryType(info.Mode(), config) || !matchMIMEFilter(path, info, config) {
 					continue
 				}
 
 				// 应用配置中的搜索限制
-				if shouldSkipFile(path, info, config) {
+				entry := fs.FileInfoToDirEntry(info)
+				if shouldSkipFile(path, entry, config) || hiddenOnlyRejects(path, entry, config) {
 					continue
 				}
 
-				// 检查文件是否被修改
-				if info.ModTime() != fileIndex.ModTime {
-					// 如果文件被修改，更新索引
-					fileIndex = FileIndex{
+				// 检查文件是否被修改，搜索结束后统一刷新索引
+				if !info.ModTime().Equal(fileIndex.ModTime) {
+					updates = append(updates, FileIndex{
 						Path:        path,
 						Name:        info.Name(),
 						Size:        info.Size(),
 						ModTime:     info.ModTime(),
 						IsDir:       info.IsDir(),
+						Type:        EntryTypeOf(info.Mode()),
 						Permissions: info.Mode(),
-					}
-					idx.fileIndices[path] = fileIndex
+					})
 				}
 
 				fileInfo, err := GetFileInfo(path, info, config)
-				if err != nil {
+				if err != nil || !limit.take() {
 					continue
 				}
 				fileInfo.MatchType = "filename"
-				results[path] = fileInfo
+				results.add(fileInfo)
 			}
 		}
 	}
 
-	return results, nil
+	idx.refresh(snap, updates)
+	return results.collected(), nil
 }
@@ -0,0 +1,180 @@
+package finder
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestIndexer 创建独立的空索引，避免测试之间共享 GetIndexer 的全局实例
+func newTestIndexer() *Indexer {
+	idx := &Indexer{}
+	idx.snapshot.Store(&indexSnapshot{
+		fileIndices: make(map[string]FileIndex),
+		nameIndices: make(map[string][]string),
+	})
+	return idx
+}
+
+// newTestConfig 返回以 root 为起始目录的配置
+func newTestConfig(root string) *SearchConfig {
+	config := NewDefaultConfig()
+	config.StartDir = root
+	return config
+}
+
+// makeTree 在 root 下创建 dirs 个目录，每个目录 filesPerDir 个空文件，返回创建的条目数（目录和文件）
+func makeTree(t *testing.T, root string, dirs, filesPerDir int) int {
+	t.Helper()
+	for d := 0; d < dirs; d++ {
+		dir := filepath.Join(root, fmt.Sprintf("dir%04d", d))
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for f := 0; f < filesPerDir; f++ {
+			file, err := os.Create(filepath.Join(dir, fmt.Sprintf("file%05d.txt", f)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			file.Close()
+		}
+	}
+	return dirs * (filesPerDir + 1)
+}
+
+// TestBuildIndexIndexesEveryFile 在数十万个文件的目录树上构建索引，每个条目都必须被索引
+func TestBuildIndexIndexesEveryFile(t *testing.T) {
+	if testing.Short() {
+		t.Skip("创建大量文件较慢，-short 时跳过")
+	}
+
+	root := t.TempDir()
+	created := makeTree(t, root, 400, 500)
+	want := int64(created + 1) // 加上起始目录本身
+
+	idx := newTestIndexer()
+	if err := idx.BuildIndex(context.Background(), root, newTestConfig(root)); err != nil {
+		t.Fatalf("BuildIndex: %v", err)
+	}
+
+	stats := idx.Stats()
+	if stats.Indexed != want {
+		t.Errorf("Stats().Indexed = %d, want %d", stats.Indexed, want)
+	}
+	if got := len(idx.snapshot.Load().fileIndices); int64(got) != want {
+		t.Errorf("len(fileIndices) = %d, want %d", got, want)
+	}
+	if stats.Errors != 0 {
+		t.Errorf("Stats().Errors = %d, want 0", stats.Errors)
+	}
+}
+
+// TestIndexerSearchDuringRebuild 多个协程持续搜索的同时反复重建索引和刷新过期条目，需配合 -race 运行
+func TestIndexerSearchDuringRebuild(t *testing.T) {
+	root := t.TempDir()
+	created := makeTree(t, root, 20, 50)
+	config := newTestConfig(root)
+
+	idx := newTestIndexer()
+	if err := idx.BuildIndex(context.Background(), root, config); err != nil {
+		t.Fatalf("BuildIndex: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				results, err := idx.Search(context.Background(), "file0001", config)
+				if err != nil {
+					errs <- err
+					return
+				}
+				// 每个目录中名称包含 file0001 的文件有 file00010 ~ file00019 共 10 个
+				if len(results) != 20*10 {
+					errs <- fmt.Errorf("搜索到 %d 个结果，应为 %d", len(results), 20*10)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 5; i++ {
+		// 修改部分文件的时间，使搜索产生过期条目并触发 refresh
+		mtime := time.Now().Add(time.Duration(i+1) * time.Second)
+		for d := 0; d < 20; d++ {
+			path := filepath.Join(root, fmt.Sprintf("dir%04d", d), "file00010.txt")
+			if err := os.Chtimes(path, mtime, mtime); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := idx.BuildIndex(context.Background(), root, config); err != nil {
+			t.Fatalf("BuildIndex: %v", err)
+		}
+	}
+	cancel()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if got, want := idx.Stats().Indexed, int64(created+1); got != want {
+		t.Errorf("Stats().Indexed = %d, want %d", got, want)
+	}
+}
+
+// TestRefreshDropsStaleGeneration 基于旧快照的更新在索引重建后应被丢弃，基于当前快照的更新应写入 overlay 并生效
+func TestRefreshDropsStaleGeneration(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root, 1, 3)
+	config := newTestConfig(root)
+	path := filepath.Join(root, "dir0000", "file00000.txt")
+
+	idx := newTestIndexer()
+	if err := idx.BuildIndex(context.Background(), root, config); err != nil {
+		t.Fatalf("BuildIndex: %v", err)
+	}
+	stale := idx.snapshot.Load()
+	if err := idx.BuildIndex(context.Background(), root, config); err != nil {
+		t.Fatalf("BuildIndex: %v", err)
+	}
+	current := idx.snapshot.Load()
+	if current.generation != stale.generation+1 {
+		t.Fatalf("重建后 generation = %d, want %d", current.generation, stale.generation+1)
+	}
+
+	update := current.fileIndices[path]
+	update.Size = 12345
+
+	idx.refresh(stale, []FileIndex{update})
+	if got, _ := idx.snapshot.Load().lookup(path); got.Size != 0 {
+		t.Errorf("基于旧快照的更新被应用, Size = %d, want 0", got.Size)
+	}
+
+	idx.refresh(current, []FileIndex{update})
+	if idx.snapshot.Load() != current {
+		t.Error("刷新替换了当前快照")
+	}
+	if got, _ := current.lookup(path); got.Size != 12345 {
+		t.Errorf("基于当前快照的更新未生效, Size = %d, want 12345", got.Size)
+	}
+	if got := len(current.fileIndices); got != 5 || current.fileIndices[path].Size != 0 {
+		t.Error("刷新修改了快照的路径索引")
+	}
+
+	// 重建后的快照不包含之前刷新的条目
+	if err := idx.BuildIndex(context.Background(), root, config); err != nil {
+		t.Fatalf("BuildIndex: %v", err)
+	}
+	if got, _ := idx.snapshot.Load().lookup(path); got.Size != 0 {
+		t.Errorf("重建后 Size = %d, want 0", got.Size)
+	}
+}
@@ -0,0 +1,35 @@
+package finder
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// ioprio_set(2) 的参数
+const (
+	ioprioWhoProcess = 1  // IOPRIO_WHO_PROCESS，按线程 ID 设置
+	ioprioClassIdle  = 3  // IOPRIO_CLASS_IDLE，仅在磁盘空闲时获得 I/O
+	ioprioClassShift = 13 // IOPRIO_CLASS_SHIFT
+)
+
+// SetIdleIOPriority 将进程的 I/O 调度类设为 idle
+// I/O 优先级按线程生效，因此逐个设置 /proc/self/task 下已有的线程，之后创建的线程会继承
+func SetIdleIOPriority() error {
+	tasks, err := os.ReadDir("/proc/self/task")
+	if err != nil {
+		return err
+	}
+	prio := uintptr(ioprioClassIdle << ioprioClassShift)
+	for _, task := range tasks {
+		tid, err := strconv.Atoi(task.Name())
+		if err != nil {
+			continue
+		}
+		_, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), prio)
+		if errno != 0 && errno != syscall.ESRCH {
+			return errno
+		}
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package finder
+
+// SetIdleIOPriority 非 Linux 系统不支持 ioprio_set，直接返回
+func SetIdleIOPriority() error {
+	return nil
+}
@@ -1,137 +1,201 @@
 package finder
 
 import (
+	"context"
 	"file-finder/internal/parser"
 	"file-finder/internal/search"
 	"file-finder/internal/utils"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 )
 
-func FindFilesByKeyword(keyword string, config *SearchConfig) (map[string]FileInfo, error) {
+// FindFilesByKeyword 按关键字搜索文件名和/或内容
+// ctx 取消或超时后停止搜索，返回已找到的部分结果
+func FindFilesByKeyword(ctx context.Context, keyword string, config *SearchConfig) (map[string]FileInfo, error) {
 	indexer := GetIndexer()
 
-	// 如果是全局搜索或者索引不存在，先构建索引
-	if config.GlobalSearch || len(indexer.fileIndices) == 0 {
-		if err := indexer.BuildIndex(config.StartDir, config); err != nil {
+	// 如果是全局搜索或者索引不存在，先构建索引（仅搜索内容时不使用索引）
+	if config.SearchMode != "content" && (config.GlobalSearch || indexer.Empty()) {
+		if err := indexer.BuildIndex(ctx, config.StartDir, config); err != nil {
 			return nil, err
 		}
 	}
 
 	// 根据搜索模式执行不同的搜索策略
+	// 设置了 config.OnResult 时结果流式输出，返回值为空
 	switch config.SearchMode {
 	case "content":
-		return findByContentOnly(keyword, config)
+		return findByContentOnly(ctx, keyword, config, config.OnResult)
 	case "both":
-		return findByBoth(keyword, config)
+		return findByBoth(ctx, keyword, config, config.OnResult)
 	default: // "filename"
-		return indexer.Search(keyword, config)
+		return indexer.Search(ctx, keyword, config)
 	}
 }
 
 // findByContentOnly 仅搜索文件内容
-func findByContentOnly(keyword string, config *SearchConfig) (map[string]FileInfo, error) {
-	// 收集所有待搜索的文件路径
-	var filePaths []string
-	textParser := parser.NewTextParser(config.MaxContentSize)
-
-	err := filepath.Walk(config.StartDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // 忽略错误，继续处理其他文件
-		}
+// 遍历器输出的条目直接交给工作协程搜索，不再先收集全部路径
+// 启用检查点时从上次中断处继续，并与之前找到的结果合并
+// emit 不为 nil 时结果立即交给 emit
+func findByContentOnly(ctx context.Context, keyword string, config *SearchConfig, emit func(FileInfo)) (map[string]FileInfo, error) {
+	phase := config.Checkpoint.phase("content:" + config.StartDir)
+	results := newResultCollector(emit)
+	results.addAll(phase.results())
+	if phase.isDone() {
+		return results.collected(), nil
+	}
 
-		// 跳过目录
-		if info.IsDir() {
-			// 检查是否需要排除此目录
-			if shouldExcludeDir(path, config.ExcludeDirs) {
-				return filepath.SkipDir
-			}
-			return nil
-		}
+	ctx, limit, stop := newResultLimit(ctx, config)
+	defer stop()
 
-		// 检查文件大小限制
-		if config.SizeLimit > 0 && info.Size() > config.SizeLimit {
-			return nil
-		}
+	entries, err := NewWalker(config).resume(phase).Walk(ctx, config.StartDir)
+	if err != nil {
+		return nil, err
+	}
 
-		// 检查文件类型
-		if len(config.FileTypes) > 0 && !isAllowedFileType(path, config.FileTypes) {
-			return nil
-		}
+	var wg sync.WaitGroup
+	for i := 0; i < config.readWorkers(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			textParser := parser.NewTextParser(config.MaxContentSize)
 
-		// 检查是否为文本文件
-		if !textParser.IsTextFile(path) {
-			return nil
-		}
+			for entry := range entries {
+				if ctx.Err() != nil {
+					continue
+				}
+				if isContentCandidate(entry, config) {
+					// 检测 MIME 类型和读取内容都会打开文件，先取得读取配额
+					if !config.Throttle.acquire(ctx) {
+						continue
+					}
+					start := time.Now()
+					var fileInfo FileInfo
+					found, read := false, int64(0)
+					if isTextCandidate(entry, config) {
+						fileInfo, found = searchFileContent(entry.Path, keyword, config, textParser)
+						read = contentReadSize(entry, config)
+					}
+					config.Throttle.release(read, time.Since(start))
+					if found {
+						if !limit.take() {
+							continue
+						}
+						phase.addResult(fileInfo)
+						results.add(fileInfo)
+					}
+				}
+				entry.Done()
+			}
+		}()
+	}
 
-		// 收集文件路径
-		filePaths = append(filePaths, path)
-		return nil
-	})
+	wg.Wait()
+	if ctx.Err() == nil {
+		phase.finish()
+	}
+	return results.collected(), nil
+}
 
+// contentReadSize 估算内容搜索读取的字节数，超过 -M 限制的文件不会被读取
+func contentReadSize(entry *WalkEntry, config *SearchConfig) int64 {
+	info, err := entry.Info()
 	if err != nil {
-		return nil, err
+		return 0
 	}
+	if config.MaxContentSize > 0 && info.Size() > config.MaxContentSize {
+		return 0
+	}
+	return info.Size()
+}
 
-	// 根据配置选择并发或串行搜索
-	if config.Concurrent {
-		return searchFileContentConcurrent(filePaths, keyword, config), nil
+// isContentCandidate 检查条目是否需要进行内容搜索，只检查类型和大小，不打开文件
+func isContentCandidate(entry *WalkEntry, config *SearchConfig) bool {
+	// 内容搜索仅针对普通文件，同时遵循 -type 过滤
+	if !entry.Type().IsRegular() || !matchEntryType(entry.Type(), config) {
+		return false
 	}
 
-	// 串行搜索
-	results := make(map[string]FileInfo)
-	for _, path := range filePaths {
-		fileInfo, found := searchFileContent(path, keyword, config, textParser)
-		if found {
-			results[path] = fileInfo
+	// 检查文件大小限制
+	if config.SizeLimit > 0 {
+		info, err := entry.Info()
+		if err != nil || info.Size() > config.SizeLimit {
+			return false
 		}
 	}
-	return results, nil
+	return true
+}
+
+// isTextCandidate 根据文件头检测内容类型，仅搜索文本文件，调用方需先取得读取配额
+func isTextCandidate(entry *WalkEntry, config *SearchConfig) bool {
+	// 使用遍历器获取的文件信息，-follow 时为链接目标的信息，链接指向的文件同样会被搜索
+	info, err := entry.Info()
+	if err != nil {
+		return false
+	}
+	mime, err := parser.DetectMIME(entry.Path, info)
+	if err != nil || !parser.IsTextMIME(mime) {
+		return false
+	}
+	if len(config.MimeTypes) > 0 && !parser.MatchMIME(mime, config.MimeTypes) {
+		return false
+	}
+	return true
 }
 
 // findByBoth 同时搜索文件名和内容
-func findByBoth(keyword string, config *SearchConfig) (map[string]FileInfo, error) {
+// 流式输出时，内容匹配的结果立即输出（同时匹配文件名的标记为 both），仅匹配文件名的结果在内容搜索结束后输出
+func findByBoth(ctx context.Context, keyword string, config *SearchConfig, emit func(FileInfo)) (map[string]FileInfo, error) {
 	// 先获取文件名匹配的结果
 	indexer := GetIndexer()
-	filenameResults, err := indexer.Search(keyword, config)
+	filenameResults, err := indexer.search(ctx, keyword, config, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	// 再获取内容匹配的结果
-	contentResults, err := findByContentOnly(keyword, config)
+	results := newResultCollector(emit)
+	var mu sync.Mutex
+	contentMatched := make(map[string]bool)
+
+	// 再获取内容匹配的结果，与文件名匹配的结果合并
+	_, err = findByContentOnly(ctx, keyword, config, func(info FileInfo) {
+		mu.Lock()
+		contentMatched[info.Path] = true
+		mu.Unlock()
+		results.add(mergeMatch(filenameResults, info))
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// 合并结果
-	results := make(map[string]FileInfo)
-
-	// 添加文件名匹配的结果
+	// 添加仅匹配文件名的结果
 	for path, info := range filenameResults {
-		info.MatchType = "filename"
-		results[path] = info
-	}
-
-	// 添加内容匹配的结果
-	for path, info := range contentResults {
-		if existing, exists := results[path]; exists {
-			// 如果文件既匹配文件名又匹配内容
-			existing.MatchType = "both"
-			existing.MatchLines = info.MatchLines
-			existing.MatchCount = info.MatchCount
-			existing.Context = info.Context
-			results[path] = existing
-		} else {
-			info.MatchType = "content"
-			results[path] = info
+		if !contentMatched[path] {
+			info.MatchType = "filename"
+			results.add(info)
 		}
 	}
 
-	return results, nil
+	return results.collected(), nil
+}
+
+// mergeMatch 合并内容匹配结果，文件同时匹配文件名时标记为 both
+func mergeMatch(filenameResults map[string]FileInfo, info FileInfo) FileInfo {
+	existing, exists := filenameResults[info.Path]
+	if !exists {
+		info.MatchType = "content"
+		return info
+	}
+	// 如果文件既匹配文件名又匹配内容
+	existing.MatchType = "both"
+	existing.MatchLines = info.MatchLines
+	existing.MatchCount = info.MatchCount
+	existing.Context = info.Context
+	existing.Matches = info.Matches
+	return existing
 }
 
 // searchFileContent 搜索单个文件的内容
@@ -149,7 +213,8 @@ func searchFileContent(filePath, keyword string, config *SearchConfig, textParse
 	}
 
 	// 使用Boyer-Moore算法搜索
-	contextSearch := search.NewContextSearch(keyword, config.CaseSensitive, config.ContextLines)
+	before, after := config.contextRange()
+	contextSearch := search.NewContextSearchRange(keyword, config.CaseSensitive, before, after)
 	matches := contextSearch.SearchWithContext(lines)
 
 	if len(matches) == 0 {
@@ -162,101 +227,51 @@ func searchFileContent(filePath, keyword string, config *SearchConfig, textParse
 		Size:        info.Size(),
 		ModTime:     info.ModTime().Format("2006-01-02 15:04:05"),
 		Permissions: info.Mode().String(),
+		Type:        EntryTypeOf(info.Mode()),
+		MimeType:    detectMIME(filePath, info),
 		Content:     "",
 	}
 
 	// 添加匹配信息
-	fileInfo.MatchLines = make([]int, len(matches))
-	fileInfo.Context = make([]string, 0)
+	fileInfo.setMatches(matches)
+	fileInfo.MatchType = "content"
+
+	// 记录符号链接目标（-follow 时内容搜索会经过链接）
+	if linkStat, err := os.Lstat(filePath); err == nil {
+		fileInfo.LinkTarget, _ = linkTargetOf(filePath, linkStat)
+	}
+
+	return fileInfo, true
+}
+
+// setMatches 设置匹配行，并据此生成匹配行号、匹配次数、去重后的上下文和内容预览
+func (f *FileInfo) setMatches(matches []search.ContextMatch) {
+	f.Matches = matches
+	f.MatchLines = make([]int, len(matches))
+	f.Context = make([]string, 0)
 	totalMatches := 0
 
 	for i, match := range matches {
-		fileInfo.MatchLines[i] = match.LineMatch.LineNumber
+		f.MatchLines[i] = match.LineMatch.LineNumber
 		totalMatches += match.LineMatch.Count
 
 		// 添加上下文（避免重复）
 		for _, contextLine := range match.Context {
-			if !contains(fileInfo.Context, contextLine) {
-				fileInfo.Context = append(fileInfo.Context, contextLine)
+			if !contains(f.Context, contextLine) {
+				f.Context = append(f.Context, contextLine)
 			}
 		}
 	}
-
-	fileInfo.MatchCount = totalMatches
-	fileInfo.MatchType = "content"
+	f.MatchCount = totalMatches
 
 	// 设置内容预览（显示第一个匹配的上下文）
+	f.Content = ""
 	if len(matches) > 0 && len(matches[0].Context) > 0 {
-		fileInfo.Content = strings.Join(matches[0].Context, "\n")
+		f.Content = strings.Join(matches[0].Context, "\n")
 	}
-
-	return fileInfo, true
-}
-
-// 并发搜索文件内容
-func searchFileContentConcurrent(filePaths []string, keyword string, config *SearchConfig) map[string]FileInfo {
-	results := make(map[string]FileInfo)
-	var mu sync.Mutex
-	var wg sync.WaitGroup
-
-	// 创建工作通道
-	jobs := make(chan string, len(filePaths))
-
-	// 启动工作协程
-	for i := 0; i < config.MaxWorkers; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			textParser := parser.NewTextParser(config.MaxContentSize)
-
-			for filePath := range jobs {
-				fileInfo, found := searchFileContent(filePath, keyword, config, textParser)
-				if found {
-					mu.Lock()
-					results[filePath] = fileInfo
-					mu.Unlock()
-				}
-			}
-		}()
-	}
-
-	// 发送任务
-	for _, filePath := range filePaths {
-		jobs <- filePath
-	}
-	close(jobs)
-
-	// 等待完成
-	wg.Wait()
-
-	return results
 }
 
 // 辅助函数
-func shouldExcludeDir(dirPath string, excludeDirs []string) bool {
-	dirName := filepath.Base(dirPath)
-	for _, exclude := range excludeDirs {
-		if dirName == exclude {
-			return true
-		}
-	}
-	return false
-}
-
-func isAllowedFileType(filePath string, allowedTypes []string) bool {
-	ext := strings.ToLower(filepath.Ext(filePath))
-	if ext != "" {
-		ext = ext[1:] // 移除点号
-	}
-
-	for _, allowedType := range allowedTypes {
-		if ext == strings.ToLower(allowedType) {
-			return true
-		}
-	}
-	return false
-}
-
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
 		if s == item {
@@ -0,0 +1,99 @@
+package finder
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// resultLimit 结果数量限制，达到 -max-results 后取消遍历
+type resultLimit struct {
+	max    int64
+	count  int64
+	cancel context.CancelFunc
+}
+
+// newResultLimit 创建结果数量限制，返回的 ctx 在达到上限时被取消
+// 调用方需在结束时调用 stop 释放 ctx
+func newResultLimit(ctx context.Context, config *SearchConfig) (context.Context, *resultLimit, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	return ctx, &resultLimit{max: int64(config.MaxResults), cancel: cancel}, cancel
+}
+
+// take 申请记录一个结果，超出上限时返回 false
+// 恰好达到上限时取消遍历，已在处理中的条目会被丢弃
+func (l *resultLimit) take() bool {
+	if l.max <= 0 {
+		return true
+	}
+	n := atomic.AddInt64(&l.count, 1)
+	if n > l.max {
+		return false
+	}
+	if n == l.max {
+		l.cancel()
+	}
+	return true
+}
+
+// resultCollector 收集查找结果
+// 设置了 emit（流式输出）时结果立即交给 emit，不再在内存中累积
+type resultCollector struct {
+	mu      sync.Mutex
+	results map[string]FileInfo
+	emit    func(FileInfo)
+}
+
+// newResultCollector 创建结果收集器，emit 为 nil 时累积结果
+func newResultCollector(emit func(FileInfo)) *resultCollector {
+	return &resultCollector{results: make(map[string]FileInfo), emit: emit}
+}
+
+// add 记录一个结果，可被多个协程并发调用
+func (c *resultCollector) add(info FileInfo) {
+	if c.emit != nil {
+		c.emit(info)
+		return
+	}
+	c.mu.Lock()
+	c.results[info.Path] = info
+	c.mu.Unlock()
+}
+
+// addAll 记录多个结果（如从检查点恢复的结果）
+func (c *resultCollector) addAll(results map[string]FileInfo) {
+	for _, info := range results {
+		c.add(info)
+	}
+}
+
+// collected 返回累积的结果，流式输出时为空
+func (c *resultCollector) collected() map[string]FileInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.results
+}
+
+// pathResults 收集按路径返回的查找结果（权限、时间搜索），只在单个协程中使用
+// 流式输出时获取文件信息后交给 config.OnResult，不再累积路径
+type pathResults struct {
+	paths  []string
+	config *SearchConfig
+}
+
+// add 记录一个结果路径
+func (r *pathResults) add(path string) {
+	if r.config.OnResult == nil {
+		r.paths = append(r.paths, path)
+		return
+	}
+	info, err := StatEntry(path, r.config)
+	if err != nil {
+		return
+	}
+	fileInfo, err := GetFileInfo(path, info, r.config)
+	if err != nil {
+		return
+	}
+	r.config.OnResult(fileInfo)
+}
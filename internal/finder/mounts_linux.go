@@ -0,0 +1,56 @@
+package finder
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readMounts 解析 /proc/self/mountinfo 获取各文件系统的设备号及类型
+// 行格式: 36 35 98:0 /mnt1 /mnt/parent rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+func readMounts() ([]mountEntry, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var mounts []mountEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		sep := -1
+		for i, field := range fields {
+			if field == "-" {
+				sep = i
+				break
+			}
+		}
+		if len(fields) < 5 || sep < 0 || sep+1 >= len(fields) {
+			continue
+		}
+
+		majorMinor := strings.SplitN(fields[2], ":", 2)
+		if len(majorMinor) != 2 {
+			continue
+		}
+		major, err1 := strconv.ParseUint(majorMinor[0], 10, 32)
+		minor, err2 := strconv.ParseUint(majorMinor[1], 10, 32)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+
+		mounts = append(mounts, mountEntry{
+			dev:    mkdev(major, minor),
+			fsType: fields[sep+1],
+		})
+	}
+	return mounts, scanner.Err()
+}
+
+// mkdev 按 glibc 的 makedev 规则组合设备号，与 stat 返回的 st_dev 一致
+func mkdev(major, minor uint64) uint64 {
+	return (major&0xfffff000)<<32 | (major&0x00000fff)<<8 |
+		(minor&0xffffff00)<<12 | (minor & 0x000000ff)
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package finder
+
+// readMounts 非 Linux 系统不读取挂载表
+func readMounts() ([]mountEntry, error) {
+	return nil, nil
+}
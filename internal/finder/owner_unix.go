@@ -0,0 +1,37 @@
+//go:build !windows
+
+package finder
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+	"sync"
+	"syscall"
+)
+
+// ownerNames 缓存 uid 对应的用户名，避免重复查询
+var ownerNames sync.Map
+
+// FileOwner 返回文件所有者的用户名，无法解析用户名时返回 uid，无法获取时返回空字符串
+func FileOwner(path string) string {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return ""
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return ""
+	}
+
+	uid := strconv.FormatUint(uint64(st.Uid), 10)
+	if name, ok := ownerNames.Load(uid); ok {
+		return name.(string)
+	}
+	name := uid
+	if u, err := user.LookupId(uid); err == nil {
+		name = u.Username
+	}
+	ownerNames.Store(uid, name)
+	return name
+}
@@ -0,0 +1,6 @@
+package finder
+
+// FileOwner Windows 的 FileInfo 不包含所有者信息，返回空字符串
+func FileOwner(path string) string {
+	return ""
+}
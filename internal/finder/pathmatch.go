@@ -0,0 +1,133 @@
+package finder
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// pathPattern 编译后的排除/包含模式
+type pathPattern struct {
+	segments []string // 按 "/" 分段的模式
+	anchored bool     // 含 "/" 的模式，从起始目录（或绝对路径根）开始匹配
+	absolute bool     // 绝对路径模式，与条目的绝对路径比较
+}
+
+// PathMatcher 路径匹配器，-exclude 和 -include 共用
+// 支持三种写法：
+//   - 名称或 glob（如 tmp、*.bak）：匹配路径中的任意一级名称
+//   - 相对路径（如 src/tmp、./build、logs/**/*.gz）：相对于起始目录锚定匹配
+//   - 绝对路径（如 /var/log/journal）：与条目的绝对路径锚定匹配
+//
+// 锚定模式匹配条目本身或其任一父目录，因此排除目录时其下所有文件一并排除
+type PathMatcher struct {
+	patterns []pathPattern
+}
+
+// NewPathMatcher 编译模式列表，空模式会被忽略
+func NewPathMatcher(patterns []string) *PathMatcher {
+	m := &PathMatcher{}
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+
+		compiled := pathPattern{absolute: filepath.IsAbs(p)}
+		if compiled.absolute {
+			p = p[len(filepath.VolumeName(p)):]
+		}
+		p = strings.TrimSuffix(filepath.ToSlash(p), "/")
+		if strings.HasPrefix(p, "./") {
+			compiled.anchored = true
+			p = p[2:]
+		}
+		if compiled.absolute || strings.Contains(p, "/") {
+			compiled.anchored = true
+			p = strings.TrimPrefix(p, "/")
+		}
+		if p == "" {
+			continue
+		}
+		compiled.segments = strings.Split(p, "/")
+		m.patterns = append(m.patterns, compiled)
+	}
+	return m
+}
+
+// Empty 是否没有任何模式
+func (m *PathMatcher) Empty() bool {
+	return m == nil || len(m.patterns) == 0
+}
+
+// Match 判断路径是否匹配任一模式，root 为搜索起始目录
+func (m *PathMatcher) Match(root, filePath string) bool {
+	if m.Empty() {
+		return false
+	}
+
+	var relSegments, absSegments []string
+	for _, p := range m.patterns {
+		var segments []string
+		if p.absolute {
+			if absSegments == nil {
+				absSegments = absPathSegments(filePath)
+			}
+			segments = absSegments
+		} else {
+			if relSegments == nil {
+				relSegments = relPathSegments(root, filePath)
+			}
+			segments = relSegments
+		}
+
+		if p.match(segments) {
+			return true
+		}
+	}
+	return false
+}
+
+// match 匹配分段后的路径
+func (p pathPattern) match(segments []string) bool {
+	if !p.anchored {
+		// 单个名称模式匹配任意一级
+		for _, seg := range segments {
+			if ok, _ := path.Match(p.segments[0], seg); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	// 锚定模式匹配路径本身或任一父目录
+	for i := 1; i <= len(segments); i++ {
+		if matchGlobSegments(p.segments, segments[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// relPathSegments 返回相对于起始目录的路径分段
+func relPathSegments(root, filePath string) []string {
+	rel, err := filepath.Rel(root, filePath)
+	if err != nil || rel == "." {
+		return []string{}
+	}
+	return strings.Split(filepath.ToSlash(rel), "/")
+}
+
+// absPathSegments 返回绝对路径的分段（不含卷标和根）
+func absPathSegments(filePath string) []string {
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		return []string{}
+	}
+	abs = abs[len(filepath.VolumeName(abs)):]
+	abs = strings.Trim(filepath.ToSlash(abs), "/")
+	if abs == "" {
+		return []string{}
+	}
+	return strings.Split(abs, "/")
+}
@@ -0,0 +1,50 @@
+package finder
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPathMatcher(t *testing.T) {
+	root := filepath.FromSlash("/data/project")
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		want     bool
+	}{
+		{"名称匹配任意一级", []string{"tmp"}, "/data/project/a/tmp/x.txt", true},
+		{"名称不匹配部分名称", []string{"tmp"}, "/data/project/a/tmpdir/x.txt", false},
+		{"glob 名称", []string{"*.bak"}, "/data/project/a/b.bak", true},
+		{"相对路径锚定在起始目录", []string{"src/tmp"}, "/data/project/src/tmp/x.txt", true},
+		{"相对路径不匹配更深的同名目录", []string{"src/tmp"}, "/data/project/lib/src/tmp/x.txt", false},
+		{"./ 前缀锚定", []string{"./build"}, "/data/project/build/out.o", true},
+		{"./ 前缀不匹配子目录中的同名目录", []string{"./build"}, "/data/project/a/build/out.o", false},
+		{"末尾的 / 被忽略", []string{"src/tmp/"}, "/data/project/src/tmp", true},
+		{"** 匹配任意层", []string{"logs/**/*.gz"}, "/data/project/logs/2024/01/a.gz", true},
+		{"** 匹配零层", []string{"logs/**/*.gz"}, "/data/project/logs/a.gz", true},
+		{"** 之后的 glob 不匹配", []string{"logs/**/*.gz"}, "/data/project/logs/2024/a.txt", false},
+		{"绝对路径", []string{"/var/log/journal"}, "/var/log/journal/x/system.journal", true},
+		{"绝对路径不匹配其他目录", []string{"/var/log/journal"}, "/var/log/syslog", false},
+		{"起始目录本身不匹配名称模式", []string{"project"}, "/data/project", false},
+		{"空模式被忽略", []string{"", "  "}, "/data/project/a", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewPathMatcher(tt.patterns)
+			if got := m.Match(root, filepath.FromSlash(tt.path)); got != tt.want {
+				t.Errorf("Match(%q) with %q = %v, want %v", tt.path, tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathMatcherEmpty(t *testing.T) {
+	var nilMatcher *PathMatcher
+	if !nilMatcher.Empty() || !NewPathMatcher(nil).Empty() || !NewPathMatcher([]string{" ", ""}).Empty() {
+		t.Error("没有有效模式的匹配器应为空")
+	}
+	if NewPathMatcher([]string{"tmp"}).Empty() {
+		t.Error("包含模式的匹配器不应为空")
+	}
+}
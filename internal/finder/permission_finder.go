@@ -1,8 +1,8 @@
 package finder
 
 import (
+	"context"
 	"os"
-	"path/filepath"
 )
 
 const (
@@ -10,52 +10,110 @@ const (
 	WRITE_PERM = 0222 // 写权限掩码
 )
 
+// access(2) 的检查模式
+const (
+	accessExecute = 0x1 // X_OK
+	accessWrite   = 0x2 // W_OK
+	accessRead    = 0x4 // R_OK
+)
+
 // 更新函数以使用Config并支持不同的权限检查
-func FindFilesByPermission(permType string, config *SearchConfig) ([]string, error) {
-	var results []string
-	startDir := config.StartDir
-
-	err := filepath.Walk(startDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			if os.IsPermission(err) {
-				return filepath.SkipDir
-			}
-			return nil
-		}
+// permType 按权限位过滤，可为空；config 中的 Readable/Writable/Executable
+// 则按当前进程的实际访问能力过滤，二者同时指定时需同时满足
+// ctx 取消或超时后停止遍历，返回已找到的部分结果；启用检查点时从上次中断处继续
+// 设置了 config.OnResult 时结果流式输出，返回值为空
+func FindFilesByPermission(ctx context.Context, permType string, config *SearchConfig) ([]string, error) {
+	phase := config.Checkpoint.phase("perm:" + config.StartDir)
+	results := &pathResults{config: config}
+	for _, path := range phase.paths() {
+		results.add(path)
+	}
+	if phase.isDone() {
+		return results.paths, nil
+	}
+	// 未完成目录中之前已找到的结果会再次被找到，需去重
+	found := phase.results()
 
-		// 应用配置中的搜索限制
-		if shouldSkipFile(path, info, config) {
-			if info.IsDir() {
-				return filepath.SkipDir
-			}
-			return nil
-		}
+	ctx, limit, stop := newResultLimit(ctx, config)
+	defer stop()
+
+	entries, err := NewWalker(config).resume(phase).Walk(ctx, config.StartDir)
+	if err != nil {
+		return nil, err
+	}
 
-		// 如果是目录且不包括目录，则跳过
-		if info.IsDir() && !config.IncludeDir {
-			return nil
+	for entry := range entries {
+		if ctx.Err() != nil {
+			continue
 		}
 
-		mode := info.Mode().Perm()
-		switch permType {
-		case "r":
-			// 检查读权限 - 用户、组或其他人可读
-			if mode&0444 != 0 {
-				results = append(results, path)
-			}
-		case "w":
-			// 检查写权限 - 用户、组或其他人可写
-			if mode&0222 != 0 {
-				results = append(results, path)
-			}
-		case "rw":
-			// 检查读写权限 - 用户、组或其他人可读写
-			if mode&0444 != 0 && mode&0222 != 0 {
-				results = append(results, path)
+		if _, ok := found[entry.Path]; !ok && matchPermission(entry, permType, config) {
+			if !limit.take() {
+				continue
 			}
+			phase.addResult(FileInfo{Path: entry.Path})
+			results.add(entry.Path)
 		}
-		return nil
-	})
+		entry.Done()
+	}
+
+	if ctx.Err() == nil {
+		phase.finish()
+	}
+	return results.paths, nil
+}
+
+// matchPermission 判断条目是否满足权限位和实际访问权限条件
+func matchPermission(entry *WalkEntry, permType string, config *SearchConfig) bool {
+	// 按 -type 和 -mime 过滤文件类型
+	if !matchEntryType(entry.Type(), config) {
+		return false
+	}
+
+	info, err := entry.Info()
+	if err != nil || !matchMIMEFilter(entry.Path, info, config) || !matchPermBits(permType, info.Mode().Perm()) {
+		return false
+	}
+
+	// 使用当前进程凭据进行实际的访问检查
+	accessMode := config.accessMode()
+	return accessMode == 0 || checkAccess(entry.Path, accessMode)
+}
+
+// matchPermBits 按权限位匹配，permType 为空时不做限制
+func matchPermBits(permType string, mode os.FileMode) bool {
+	switch permType {
+	case "":
+		return true
+	case "r":
+		// 检查读权限 - 用户、组或其他人可读
+		return mode&READ_PERM != 0
+	case "w":
+		// 检查写权限 - 用户、组或其他人可写
+		return mode&WRITE_PERM != 0
+	case "rw":
+		// 检查读写权限 - 用户、组或其他人可读写
+		return mode&READ_PERM != 0 && mode&WRITE_PERM != 0
+	}
+	return false
+}
+
+// accessMode 根据配置生成 access(2) 检查模式
+func (c *SearchConfig) accessMode() uint32 {
+	var mode uint32
+	if c.Readable {
+		mode |= accessRead
+	}
+	if c.Writable {
+		mode |= accessWrite
+	}
+	if c.Executable {
+		mode |= accessExecute
+	}
+	return mode
+}
 
-	return results, err
+// HasAccessFilter 是否指定了基于当前用户的实际访问权限过滤
+func (c *SearchConfig) HasAccessFilter() bool {
+	return c.accessMode() != 0
 }
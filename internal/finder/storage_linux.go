@@ -0,0 +1,52 @@
+package finder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isRotational 判断路径所在的块设备是否为机械硬盘
+// 通过 /sys/dev/block/<major>:<minor> 读取 queue/rotational，分区需读取其所属磁盘的队列信息
+// 无法确定时（tmpfs、overlay、网络文件系统等）第二个返回值为 false
+func isRotational(path string) (bool, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, false
+	}
+	dev, ok := deviceOf(info)
+	if !ok {
+		return false, false
+	}
+	major, minor := devMajor(dev), devMinor(dev)
+	if major == 0 {
+		// 匿名设备没有对应的块设备
+		return false, false
+	}
+
+	sysDir := fmt.Sprintf("/sys/dev/block/%d:%d", major, minor)
+	dirs := []string{sysDir}
+	// /sys/dev/block/<major>:<minor> 是指向设备目录的符号链接，分区目录位于所属磁盘目录之下
+	// 需先解析链接再取父目录，filepath.Join 按字面清理 ".." 只会得到 /sys/dev/block
+	if resolved, err := filepath.EvalSymlinks(sysDir); err == nil {
+		dirs = append(dirs, filepath.Dir(resolved))
+	}
+	for _, dir := range dirs {
+		data, err := os.ReadFile(filepath.Join(dir, "queue", "rotational"))
+		if err == nil {
+			return strings.TrimSpace(string(data)) == "1", true
+		}
+	}
+	return false, false
+}
+
+// devMajor 按 glibc 的 major 规则从设备号中取主设备号，与 mkdev 对应
+func devMajor(dev uint64) uint64 {
+	return (dev>>32)&0xfffff000 | (dev>>8)&0x00000fff
+}
+
+// devMinor 按 glibc 的 minor 规则从设备号中取次设备号
+func devMinor(dev uint64) uint64 {
+	return (dev>>12)&0xffffff00 | dev&0x000000ff
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package finder
+
+// isRotational 非 Linux 系统无法判断存储类型
+func isRotational(path string) (bool, bool) {
+	return false, false
+}
@@ -0,0 +1,193 @@
+package finder
+
+import (
+	"context"
+	"file-finder/internal/utils"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// -nice 模式的默认限速
+const (
+	niceBytesPerSec = 10 * 1024 * 1024 // 每秒读取 10MB
+	niceFilesPerSec = 200              // 每秒读取 200 个文件
+)
+
+// 自适应并发的参数
+const (
+	latencyChunk     = 64 * 1024            // 按每 64KB 折算读取延迟，避免大文件误判
+	latencyWarmup    = 8                    // 取得基线前的采样数
+	latencyWindow    = 16                   // 每隔多少个采样调整一次并发数
+	latencyFloor     = 5 * time.Millisecond // 低于此延迟时不降低并发
+	latencyHighRatio = 3.0                  // 延迟超过基线的倍数时降低并发
+	latencyLowRatio  = 1.5                  // 延迟回落到基线的倍数以内时恢复并发
+)
+
+// IOThrottle 内容搜索的读取限速器
+// 按每秒字节数和每秒文件数限制读取速度；-nice 模式下根据读取延迟自适应地减少同时读取的协程数，
+// 同时统计实际吞吐量用于结束时输出
+type IOThrottle struct {
+	bytesPerSec int64
+	filesPerSec int64
+	adaptive    bool
+	maxWorkers  int
+
+	mu       sync.Mutex
+	start    time.Time
+	files    int64
+	bytes    int64
+	active   int     // 正在读取的协程数
+	limit    int     // 当前允许同时读取的协程数
+	minLimit int     // 运行期间降到的最低并发数
+	latency  float64 // 折算后读取延迟的指数移动平均，单位秒
+	baseline float64 // 观察到的最低延迟
+	samples  int
+}
+
+// NewIOThrottle 根据配置创建读取限速器，未设置限速时只统计吞吐量
+func NewIOThrottle(config *SearchConfig) *IOThrottle {
+	t := &IOThrottle{
+		bytesPerSec: config.ReadBytesPerSec,
+		filesPerSec: int64(config.ReadFilesPerSec),
+		adaptive:    config.Nice,
+		maxWorkers:  config.readWorkers(),
+	}
+	if config.Nice {
+		if t.bytesPerSec <= 0 {
+			t.bytesPerSec = niceBytesPerSec
+		}
+		if t.filesPerSec <= 0 {
+			t.filesPerSec = niceFilesPerSec
+		}
+	}
+	t.limit = t.maxWorkers
+	t.minLimit = t.maxWorkers
+	return t
+}
+
+// acquire 等待读取配额和空闲的读取槽位，ctx 取消时返回 false
+func (t *IOThrottle) acquire(ctx context.Context) bool {
+	if t == nil {
+		return ctx.Err() == nil
+	}
+	for {
+		t.mu.Lock()
+		if t.start.IsZero() {
+			t.start = time.Now()
+		}
+		wait := t.paceDelay()
+		if wait <= 0 && t.active < t.limit {
+			t.active++
+			t.files++
+			t.mu.Unlock()
+			return true
+		}
+		if wait <= 0 {
+			// 并发数已被降低，等待其他协程读取完成
+			wait = 5 * time.Millisecond
+		}
+		t.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+	}
+}
+
+// release 读取完成，记录读取的字节数和耗时
+func (t *IOThrottle) release(n int64, elapsed time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.active--
+	t.bytes += n
+	if t.adaptive {
+		t.adapt(n, elapsed)
+	}
+}
+
+// paceDelay 计算按限速还需等待的时间，调用方需持有锁
+func (t *IOThrottle) paceDelay() time.Duration {
+	elapsed := time.Since(t.start)
+	var wait time.Duration
+	if t.filesPerSec > 0 {
+		due := time.Duration(float64(t.files) / float64(t.filesPerSec) * float64(time.Second))
+		if due-elapsed > wait {
+			wait = due - elapsed
+		}
+	}
+	if t.bytesPerSec > 0 {
+		due := time.Duration(float64(t.bytes) / float64(t.bytesPerSec) * float64(time.Second))
+		if due-elapsed > wait {
+			wait = due - elapsed
+		}
+	}
+	return wait
+}
+
+// adapt 根据读取延迟调整并发数：延迟明显高于基线时说明磁盘已繁忙，逐步减少读取协程
+func (t *IOThrottle) adapt(n int64, elapsed time.Duration) {
+	chunks := float64(n)/latencyChunk + 1
+	sample := elapsed.Seconds() / chunks
+
+	if t.samples == 0 {
+		t.latency = sample
+	} else {
+		t.latency = 0.8*t.latency + 0.2*sample
+	}
+	t.samples++
+	if t.samples < latencyWarmup {
+		return
+	}
+	if t.baseline == 0 || t.latency < t.baseline {
+		t.baseline = t.latency
+	}
+	if t.samples%latencyWindow != 0 {
+		return
+	}
+
+	switch {
+	case t.latency > t.baseline*latencyHighRatio && t.latency > latencyFloor.Seconds() && t.limit > 1:
+		t.limit--
+		if t.limit < t.minLimit {
+			t.minLimit = t.limit
+		}
+		utils.Logger.Printf("[限速] 读取延迟升高 (%.1fms，基线 %.1fms)，并发数降为 %d",
+			t.latency*1000, t.baseline*1000, t.limit)
+	case t.latency < t.baseline*latencyLowRatio && t.limit < t.maxWorkers:
+		t.limit++
+		utils.Logger.Printf("[限速] 读取延迟恢复 (%.1fms)，并发数升为 %d", t.latency*1000, t.limit)
+	}
+}
+
+// Summary 返回读取吞吐量的统计，没有读取任何文件时返回空字符串
+func (t *IOThrottle) Summary() string {
+	if t == nil {
+		return ""
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.files == 0 {
+		return ""
+	}
+
+	elapsed := time.Since(t.start)
+	seconds := elapsed.Seconds()
+	if seconds <= 0 {
+		seconds = 1e-9
+	}
+	summary := fmt.Sprintf("读取 %d 个文件共 %s，耗时 %s，平均 %s/s、%.1f 个文件/s",
+		t.files, utils.FormatFileSize(t.bytes), elapsed.Round(time.Millisecond),
+		utils.FormatFileSize(int64(float64(t.bytes)/seconds)), float64(t.files)/seconds)
+	if t.adaptive && t.minLimit < t.maxWorkers {
+		summary += fmt.Sprintf("，并发数最低降至 %d/%d", t.minLimit, t.maxWorkers)
+	}
+	return summary
+}
@@ -1,42 +1,65 @@
 package finder
 
 import (
-	"os"
-	"path/filepath"
+	"context"
 	"time"
 )
 
-func FindModifiedFiles(limitTime time.Time, config *SearchConfig) ([]string, error) {
-	var results []string
-	startDir := config.StartDir
+// FindModifiedFiles 查找指定时间后修改的文件
+// ctx 取消或超时后停止遍历，返回已找到的部分结果；启用检查点时从上次中断处继续
+// 设置了 config.OnResult 时结果流式输出，返回值为空
+func FindModifiedFiles(ctx context.Context, limitTime time.Time, config *SearchConfig) ([]string, error) {
+	phase := config.Checkpoint.phase("time:" + config.StartDir)
+	results := &pathResults{config: config}
+	for _, path := range phase.paths() {
+		results.add(path)
+	}
+	if phase.isDone() {
+		return results.paths, nil
+	}
+	// 未完成目录中之前已找到的结果会再次被找到，需去重
+	found := phase.results()
 
-	err := filepath.Walk(startDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			if os.IsPermission(err) {
-				return filepath.SkipDir
-			}
-			return nil
+	ctx, limit, stop := newResultLimit(ctx, config)
+	defer stop()
+
+	entries, err := NewWalker(config).resume(phase).Walk(ctx, config.StartDir)
+	if err != nil {
+		return nil, err
+	}
+
+	for entry := range entries {
+		if ctx.Err() != nil {
+			continue
 		}
 
-		// 应用配置中的搜索限制
-		if shouldSkipFile(path, info, config) {
-			if info.IsDir() {
-				return filepath.SkipDir
+		if _, ok := found[entry.Path]; !ok && modifiedAfter(entry, limitTime, config) {
+			if !limit.take() {
+				continue
 			}
-			return nil
+			phase.addResult(FileInfo{Path: entry.Path})
+			results.add(entry.Path)
 		}
+		entry.Done()
+	}
 
-		// 如果是目录且不包括目录，则跳过
-		if info.IsDir() && !config.IncludeDir {
-			return nil
-		}
+	if ctx.Err() == nil {
+		phase.finish()
+	}
+	return results.paths, nil
+}
 
-		// 只返回指定时间后修改的文件
-		if info.ModTime().After(limitTime) {
-			results = append(results, path)
-		}
-		return nil
-	})
+// modifiedAfter 判断条目是否在指定时间后修改
+func modifiedAfter(entry *WalkEntry, limitTime time.Time, config *SearchConfig) bool {
+	// 按 -type 和 -mime 过滤文件类型
+	if !matchEntryType(entry.Type(), config) {
+		return false
+	}
 
-	return results, err
+	// 只返回指定时间后修改的文件
+	info, err := entry.Info()
+	if err != nil || !matchMIMEFilter(entry.Path, info, config) {
+		return false
+	}
+	return info.ModTime().After(limitTime)
 }
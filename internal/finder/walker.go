@@ -0,0 +1,457 @@
+package finder
+
+import (
+	"context"
+	"file-finder/internal/utils"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+// linkInfo 跟随符号链接后得到的文件信息
+// 嵌入的 FileInfo 为链接目标的信息（失效链接则为链接本身），同时记录链接目标
+type linkInfo struct {
+	os.FileInfo
+	target string // 链接指向的路径
+	broken bool   // 链接目标不存在或不可访问
+}
+
+// WalkEntry 遍历得到的条目，实现 fs.DirEntry
+// 文件信息按需获取：仅凭目录项类型即可判断时不会调用 lstat
+type WalkEntry struct {
+	Path  string // 条目路径
+	Depth int    // 相对起始目录的深度，起始目录为 0
+
+	dirent  fs.DirEntry
+	info    os.FileInfo
+	tracker *dirTracker // 启用检查点时跟踪所在目录的处理进度
+}
+
+// Name 返回条目名称
+func (e *WalkEntry) Name() string {
+	if e.dirent != nil {
+		return e.dirent.Name()
+	}
+	return filepath.Base(e.Path)
+}
+
+// IsDir 是否为目录（-follow 时指向目录的链接也视为目录）
+func (e *WalkEntry) IsDir() bool {
+	return e.Type().IsDir()
+}
+
+// Type 返回文件类型位
+func (e *WalkEntry) Type() fs.FileMode {
+	if e.info != nil || e.dirent == nil {
+		if info, err := e.Info(); err == nil {
+			return info.Mode().Type()
+		}
+		return 0
+	}
+	return e.dirent.Type()
+}
+
+// Info 返回文件信息，首次调用时执行 lstat 并缓存
+func (e *WalkEntry) Info() (os.FileInfo, error) {
+	if e.info != nil {
+		return e.info, nil
+	}
+	var info os.FileInfo
+	var err error
+	if e.dirent != nil {
+		info, err = e.dirent.Info()
+	} else {
+		info, err = os.Lstat(e.Path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	e.info = info
+	return info, nil
+}
+
+// Done 调用方处理完条目后调用，启用检查点时用于记录已完成的目录
+// 因取消等原因未处理的条目不应调用
+func (e *WalkEntry) Done() {
+	if e.tracker != nil {
+		e.tracker.done()
+	}
+}
+
+// WalkStats 遍历统计
+type WalkStats struct {
+	Dirs    int64 // 已读取的目录数
+	Files   int64 // 已输出的非目录条目数
+	Skipped int64 // 被忽略规则、排除模式等过滤掉的条目数
+	Errors  int64 // 读取失败的目录数
+}
+
+// walkDir 待读取的目录
+type walkDir struct {
+	path      string
+	depth     int
+	ancestors []fileKey   // 路径上已进入的目录，仅 -follow 时用于检测循环
+	parent    *dirTracker // 启用检查点时父目录的处理进度
+}
+
+// Walker 并发目录遍历器，所有查找函数共用
+// 使用有界协程池并行读取目录，遍历时应用忽略规则、排除/包含模式、隐藏文件策略、
+// 深度限制和文件系统边界，通过通道将条目流式交给调用方
+// 每个 Walker 只能调用一次 Walk
+type Walker struct {
+	config  *SearchConfig
+	workers int
+	ignore  *IgnoreMatcher
+
+	rootDev    uint64            // 起始目录所在文件系统的设备号
+	hasRootDev bool              // 是否取得了起始目录的设备号
+	skipDevs   map[uint64]string // 需要跳过的伪文件系统和远程文件系统
+	checkpoint *checkpointPhase  // 检查点进度，已完成的目录不再输出条目
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	queue     []walkDir
+	pending   int // 队列中以及正在读取的目录数
+	cancelled bool
+
+	dirs    int64
+	files   int64
+	skipped int64
+	errors  int64
+}
+
+// NewWalker 创建目录遍历器
+func NewWalker(config *SearchConfig) *Walker {
+	w := &Walker{
+		config:  config,
+		workers: config.walkWorkers(),
+	}
+	w.cond = sync.NewCond(&w.mu)
+	return w
+}
+
+// Walk 从 root 开始遍历，返回条目通道
+// 通道在遍历完成或 ctx 取消后关闭；调用方提前退出时必须取消 ctx 以释放遍历协程
+func (w *Walker) Walk(ctx context.Context, root string) (<-chan *WalkEntry, error) {
+	info, err := os.Lstat(root)
+	if err != nil {
+		return nil, err
+	}
+
+	rootEntry := &WalkEntry{Path: root, info: info}
+	if w.config.FollowLinks && info.Mode()&os.ModeSymlink != 0 {
+		rootEntry.info = followLink(root, info)
+	}
+
+	w.ignore = NewIgnoreMatcher(root, w.config)
+	w.rootDev, w.hasRootDev = deviceOf(rootEntry.info)
+	if !w.config.AllFilesystems {
+		w.skipDevs = skippedDevices(w.config)
+		// 起始目录本身位于被跳过的文件系统时，视为显式指定
+		delete(w.skipDevs, w.rootDev)
+	}
+
+	out := make(chan *WalkEntry, 1024)
+	if !w.accept(rootEntry) {
+		close(out)
+		return out, nil
+	}
+
+	// 起始目录的整棵子树在之前的搜索中已完成时不再遍历
+	if _, treeDone := w.checkpoint.dirDone(root); rootEntry.IsDir() && !treeDone {
+		var ancestors []fileKey
+		if key, ok := fileKeyOf(root, rootEntry.info); ok && w.config.FollowLinks {
+			ancestors = []fileKey{key}
+		}
+		w.push(walkDir{path: root, depth: 0, ancestors: ancestors})
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			w.mu.Lock()
+			w.cancelled = true
+			w.cond.Broadcast()
+			w.mu.Unlock()
+		case <-finished:
+		}
+	}()
+
+	var wg sync.WaitGroup
+	go func() {
+		defer close(out)
+		defer close(finished)
+
+		if !hiddenOnlyRejects(root, rootEntry, w.config) && !w.send(ctx, out, rootEntry) {
+			w.cancel()
+		}
+
+		for i := 0; i < w.workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					dir, ok := w.next()
+					if !ok {
+						return
+					}
+					w.readDir(ctx, out, dir)
+					w.finish()
+				}
+			}()
+		}
+		wg.Wait()
+	}()
+
+	return out, nil
+}
+
+// resume 启用检查点：跳过已完成目录中的条目，并在目录处理完后记录
+// 调用方需对每个处理完的条目调用 Done
+func (w *Walker) resume(phase *checkpointPhase) *Walker {
+	w.checkpoint = phase
+	return w
+}
+
+// Stats 返回遍历统计
+func (w *Walker) Stats() WalkStats {
+	return WalkStats{
+		Dirs:    atomic.LoadInt64(&w.dirs),
+		Files:   atomic.LoadInt64(&w.files),
+		Skipped: atomic.LoadInt64(&w.skipped),
+		Errors:  atomic.LoadInt64(&w.errors),
+	}
+}
+
+// push 将目录加入待读取队列
+func (w *Walker) push(dir walkDir) {
+	w.mu.Lock()
+	w.queue = append(w.queue, dir)
+	w.pending++
+	w.mu.Unlock()
+	w.cond.Signal()
+}
+
+// next 取出下一个待读取的目录，队列为空且没有正在读取的目录时返回 false
+// 按后进先出取出，使遍历接近深度优先，减少队列占用的内存
+func (w *Walker) next() (walkDir, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for len(w.queue) == 0 && w.pending > 0 && !w.cancelled {
+		w.cond.Wait()
+	}
+	if w.cancelled || len(w.queue) == 0 {
+		return walkDir{}, false
+	}
+	dir := w.queue[len(w.queue)-1]
+	w.queue = w.queue[:len(w.queue)-1]
+	return dir, true
+}
+
+// finish 标记一个目录读取完成
+func (w *Walker) finish() {
+	w.mu.Lock()
+	w.pending--
+	if w.pending == 0 {
+		w.cond.Broadcast()
+	}
+	w.mu.Unlock()
+}
+
+// cancel 停止所有遍历协程
+func (w *Walker) cancel() {
+	w.mu.Lock()
+	w.cancelled = true
+	w.cond.Broadcast()
+	w.mu.Unlock()
+}
+
+// send 输出条目，ctx 取消时返回 false
+func (w *Walker) send(ctx context.Context, out chan<- *WalkEntry, entry *WalkEntry) bool {
+	// 条目交给调用方后不再访问，先记录类型
+	isDir := entry.IsDir()
+	select {
+	case out <- entry:
+		if !isDir {
+			atomic.AddInt64(&w.files, 1)
+		}
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// readDir 读取单个目录，输出通过过滤的条目并将子目录加入队列
+func (w *Walker) readDir(ctx context.Context, out chan<- *WalkEntry, dir walkDir) {
+	dirents, err := os.ReadDir(dir.path)
+	if err != nil {
+		// 部分读取时仍处理已读到的条目
+		atomic.AddInt64(&w.errors, 1)
+		utils.Logger.Printf("[遍历] 读取目录失败: %s: %v", dir.path, err)
+	}
+	atomic.AddInt64(&w.dirs, 1)
+
+	// 条目已完成的目录只继续向下遍历子目录，不再输出条目
+	var tracker *dirTracker
+	skipEntries, _ := w.checkpoint.dirDone(dir.path)
+	if w.checkpoint != nil {
+		tracker = newDirTracker(dir.path, dir.parent, w.checkpoint)
+	}
+
+	for _, dirent := range dirents {
+		if ctx.Err() != nil {
+			return
+		}
+
+		entry := &WalkEntry{
+			Path:   filepath.Join(dir.path, dirent.Name()),
+			Depth:  dir.depth + 1,
+			dirent: dirent,
+		}
+		if w.config.FollowLinks && dirent.Type()&os.ModeSymlink != 0 {
+			if info, err := dirent.Info(); err == nil {
+				entry.info = followLink(entry.Path, info)
+			}
+		}
+
+		if !w.accept(entry) {
+			atomic.AddInt64(&w.skipped, 1)
+			continue
+		}
+
+		if entry.IsDir() {
+			child, ok := w.descend(entry, dir)
+			if !ok {
+				continue
+			}
+			// 达到深度限制的目录不再读取其内容，整棵子树已完成的目录不再进入
+			if w.config.MaxDepth <= 0 || entry.Depth < w.config.MaxDepth {
+				_, treeDone := w.checkpoint.dirDone(child.path)
+				if tracker != nil {
+					tracker.addChild(child.path, !treeDone)
+					child.parent = tracker
+				}
+				if !treeDone {
+					w.push(child)
+				}
+			}
+		}
+
+		if skipEntries || hiddenOnlyRejects(entry.Path, entry, w.config) {
+			continue
+		}
+		if tracker != nil {
+			entry.tracker = tracker
+			tracker.add()
+		}
+		if !w.send(ctx, out, entry) {
+			return
+		}
+	}
+
+	// 中途取消时不释放，目录不会被记录为已完成
+	if tracker != nil {
+		tracker.done()
+	}
+}
+
+// accept 应用遍历阶段的过滤条件，被拒绝的目录不会继续向下遍历
+func (w *Walker) accept(entry *WalkEntry) bool {
+	// 遵循 .gitignore/.ignore/.finderignore 中的忽略规则
+	if w.ignore.Match(entry.Path, entry.IsDir()) {
+		return false
+	}
+	// 应用排除/包含模式、隐藏文件策略、扩展名和深度限制
+	return !shouldSkipFile(entry.Path, entry, w.config)
+}
+
+// descend 检查文件系统边界和符号链接循环，返回子目录的队列项
+func (w *Walker) descend(entry *WalkEntry, parent walkDir) (walkDir, bool) {
+	child := walkDir{path: entry.Path, depth: entry.Depth, ancestors: parent.ancestors}
+
+	if !w.config.SameFilesystem && len(w.skipDevs) == 0 && !w.config.FollowLinks {
+		return child, true
+	}
+	info, err := entry.Info()
+	if err != nil {
+		return child, false
+	}
+
+	if w.crossesBoundary(entry.Path, info) {
+		return child, false
+	}
+
+	// 检测符号链接造成的目录循环，不跟随链接时不会产生循环
+	if w.config.FollowLinks {
+		if key, ok := fileKeyOf(entry.Path, info); ok {
+			for _, ancestor := range parent.ancestors {
+				if ancestor == key {
+					utils.Logger.Printf("[遍历] 检测到符号链接循环，跳过: %s", entry.Path)
+					return child, false
+				}
+			}
+			child.ancestors = append(append([]fileKey(nil), parent.ancestors...), key)
+		}
+	}
+	return child, true
+}
+
+// crossesBoundary 判断目录是否位于需要跳过的文件系统上
+// -xdev 时跳过与起始目录设备号不同的目录，默认跳过伪文件系统和远程文件系统
+func (w *Walker) crossesBoundary(path string, info os.FileInfo) bool {
+	if !w.config.SameFilesystem && len(w.skipDevs) == 0 {
+		return false
+	}
+	dev, ok := deviceOf(info)
+	if !ok {
+		return false
+	}
+	if w.config.SameFilesystem && w.hasRootDev && dev != w.rootDev {
+		utils.Logger.Printf("[遍历] -xdev 跳过其他文件系统: %s", path)
+		return true
+	}
+	if fsType, skip := w.skipDevs[dev]; skip {
+		utils.Logger.Printf("[遍历] 跳过 %s 文件系统: %s", fsType, path)
+		return true
+	}
+	return false
+}
+
+// followLink 跟随符号链接，返回目标的文件信息
+func followLink(path string, info os.FileInfo) os.FileInfo {
+	target, _ := os.Readlink(path)
+	targetInfo, err := os.Stat(path)
+	if err != nil {
+		utils.Logger.Printf("[遍历] 失效的符号链接: %s -> %s", path, target)
+		return &linkInfo{FileInfo: info, target: target, broken: true}
+	}
+	return &linkInfo{FileInfo: targetInfo, target: target}
+}
+
+// StatEntry 获取路径的文件信息，-follow 时跟随符号链接，否则返回链接本身的信息
+func StatEntry(path string, config *SearchConfig) (os.FileInfo, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	if config.FollowLinks && info.Mode()&os.ModeSymlink != 0 {
+		return followLink(path, info), nil
+	}
+	return info, nil
+}
+
+// linkTargetOf 返回符号链接的目标以及目标是否失效
+func linkTargetOf(path string, info os.FileInfo) (string, bool) {
+	if li, ok := info.(*linkInfo); ok {
+		return li.target, li.broken
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return "", false
+	}
+	target, _ := os.Readlink(path)
+	_, err := os.Stat(path)
+	return target, err != nil
+}
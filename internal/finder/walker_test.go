@@ -0,0 +1,191 @@
+package finder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// walkPaths 遍历 config.StartDir，返回相对于起始目录的条目路径（起始目录为 "."），按路径排序
+func walkPaths(t *testing.T, config *SearchConfig) []string {
+	t.Helper()
+	entries, err := NewWalker(config).Walk(context.Background(), config.StartDir)
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	var paths []string
+	for entry := range entries {
+		rel, err := filepath.Rel(config.StartDir, entry.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// symlink 创建符号链接，不支持时跳过测试
+func symlink(t *testing.T, target, link string) {
+	t.Helper()
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("无法创建符号链接: %v", err)
+	}
+}
+
+func TestWalker(t *testing.T) {
+	root := t.TempDir()
+	for _, file := range []string{
+		"a.txt", "b.go", "src/main.go", "src/tmp/x.txt", "src/deep/er/y.txt",
+		".env", ".config/app.conf", ".cache/big.bin", "node_modules/m/index.js", "logs/app.log",
+	} {
+		writeFile(t, filepath.Join(root, filepath.FromSlash(file)), "x")
+	}
+	writeFile(t, filepath.Join(root, ".gitignore"), "*.log\n")
+
+	tests := []struct {
+		name  string
+		setup func(c *SearchConfig)
+		want  []string
+	}{
+		{
+			name:  "默认策略跳过噪声目录、默认排除目录和忽略的文件",
+			setup: func(c *SearchConfig) {},
+			want: []string{".", ".config", ".config/app.conf", ".env", ".gitignore", "a.txt", "b.go",
+				"logs", "src", "src/deep", "src/deep/er", "src/deep/er/y.txt", "src/main.go", "src/tmp", "src/tmp/x.txt"},
+		},
+		{
+			name:  "深度限制",
+			setup: func(c *SearchConfig) { c.MaxDepth = 1; c.HiddenMode = HiddenExclude },
+			want:  []string{".", "a.txt", "b.go", "logs", "src"},
+		},
+		{
+			name: "锚定的排除模式剪掉整个目录",
+			setup: func(c *SearchConfig) {
+				c.ExcludeDirs = append(c.ExcludeDirs, "src/deep")
+				c.HiddenMode = HiddenExclude
+			},
+			want: []string{".", "a.txt", "b.go", "logs", "src", "src/main.go", "src/tmp", "src/tmp/x.txt"},
+		},
+		{
+			name: "包含模式只过滤文件",
+			setup: func(c *SearchConfig) {
+				c.IncludeGlobs = []string{"*.go"}
+				c.HiddenMode = HiddenExclude
+			},
+			want: []string{".", "b.go", "logs", "src", "src/deep", "src/deep/er", "src/main.go", "src/tmp"},
+		},
+		{
+			name:  "只保留隐藏条目，非隐藏目录只用于向下遍历",
+			setup: func(c *SearchConfig) { c.HiddenMode = HiddenOnly },
+			want:  []string{".config", ".config/app.conf", ".env", ".gitignore"},
+		},
+		{
+			name: "包含所有隐藏条目且不读取忽略文件",
+			setup: func(c *SearchConfig) {
+				c.HiddenMode = HiddenInclude
+				c.NoIgnore = true
+				c.ExcludeDirs = nil
+				c.MaxDepth = 1
+			},
+			want: []string{".", ".cache", ".config", ".env", ".gitignore", "a.txt", "b.go", "logs", "node_modules", "src"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := newTestConfig(root)
+			tt.setup(config)
+			if got := walkPaths(t, config); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("遍历结果\n got %v\nwant %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalkerFollowLinks(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	writeFile(t, filepath.Join(root, "real/file.txt"), "x")
+	writeFile(t, filepath.Join(outside, "ext.txt"), "x")
+	symlink(t, outside, filepath.Join(root, "ext"))
+	symlink(t, "..", filepath.Join(root, "real/loop")) // 指向上级目录，形成循环
+	symlink(t, "missing", filepath.Join(root, "broken"))
+
+	config := newTestConfig(root)
+	if got, want := walkPaths(t, config), []string{".", "broken", "ext", "real", "real/file.txt", "real/loop"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("不跟随链接\n got %v\nwant %v", got, want)
+	}
+
+	config = newTestConfig(root)
+	config.FollowLinks = true
+	// 形成循环的链接被跳过，不会重复遍历
+	want := []string{".", "broken", "ext", "ext/ext.txt", "real", "real/file.txt"}
+	if got := walkPaths(t, config); !reflect.DeepEqual(got, want) {
+		t.Errorf("跟随链接\n got %v\nwant %v", got, want)
+	}
+
+	// 跟随链接时条目信息为链接目标的信息，失效的链接保留链接本身
+	entries, err := NewWalker(config).Walk(context.Background(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch filepath.Base(entry.Path) {
+		case "ext":
+			if !entry.IsDir() {
+				t.Errorf("%s 应视为目录", entry.Path)
+			}
+		case "broken":
+			if target, broken := linkTargetOf(entry.Path, info); !broken || target != "missing" {
+				t.Errorf("linkTargetOf(%s) = %q, %v, want missing, true", entry.Path, target, broken)
+			}
+		}
+	}
+}
+
+func TestWalkerStats(t *testing.T) {
+	root := t.TempDir()
+	created := makeTree(t, root, 3, 4)
+	writeFile(t, filepath.Join(root, "node_modules/x.js"), "x")
+
+	w := NewWalker(newTestConfig(root))
+	entries, err := w.Walk(context.Background(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for range entries {
+		count++
+	}
+
+	stats := w.Stats()
+	if count != created+1 {
+		t.Errorf("输出 %d 个条目, want %d", count, created+1)
+	}
+	if stats.Files != 3*4 || stats.Dirs != 4 || stats.Skipped != 1 || stats.Errors != 0 {
+		t.Errorf("Stats() = %+v, want Files 12, Dirs 4, Skipped 1, Errors 0", stats)
+	}
+}
+
+func TestWalkerCancel(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root, 20, 20)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	entries, err := NewWalker(newTestConfig(root)).Walk(ctx, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-entries
+	cancel()
+	// 取消后通道必须关闭，遍历协程退出
+	for range entries {
+	}
+}
@@ -0,0 +1,248 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// sniffLen MIME 检测读取的文件头长度
+const sniffLen = 512
+
+// magicSignature 文件头魔数签名
+type magicSignature struct {
+	offset int
+	magic  []byte
+	mime   string
+	verify func(data []byte) bool // 魔数过短时的额外校验，为 nil 时只比较魔数
+}
+
+// magicSignatures 常见文件格式的魔数表，按顺序匹配
+// net/http 的 DetectContentType 未覆盖可执行文件、数据库等格式，这里优先检测
+var magicSignatures = []magicSignature{
+	{0, []byte("\x7fELF"), "application/x-executable", nil},
+	{0, []byte("MZ"), "application/vnd.microsoft.portable-executable", isPE},
+	{0, []byte("\xfe\xed\xfa\xce"), "application/x-mach-binary", nil},
+	{0, []byte("\xfe\xed\xfa\xcf"), "application/x-mach-binary", nil},
+	{0, []byte("\xce\xfa\xed\xfe"), "application/x-mach-binary", nil},
+	{0, []byte("\xcf\xfa\xed\xfe"), "application/x-mach-binary", nil},
+	{0, []byte("\xca\xfe\xba\xbe"), "application/java-vm", nil},
+	{0, []byte("\x00asm"), "application/wasm", nil},
+	{0, []byte("SQLite format 3\x00"), "application/x-sqlite3", nil},
+	{0, []byte("%PDF-"), "application/pdf", nil},
+	{0, []byte("\x89PNG\r\n\x1a\n"), "image/png", nil},
+	{0, []byte("\xff\xd8\xff"), "image/jpeg", nil},
+	{0, []byte("GIF87a"), "image/gif", nil},
+	{0, []byte("GIF89a"), "image/gif", nil},
+	{0, []byte("PK\x03\x04"), "application/zip", nil},
+	{0, []byte("PK\x05\x06"), "application/zip", nil},
+	{0, []byte("\x1f\x8b"), "application/gzip", nil},
+	{0, []byte("BZh"), "application/x-bzip2", nil},
+	{0, []byte("\xfd7zXZ\x00"), "application/x-xz", nil},
+	{0, []byte("\x28\xb5\x2f\xfd"), "application/zstd", nil},
+	{0, []byte("7z\xbc\xaf\x27\x1c"), "application/x-7z-compressed", nil},
+	{0, []byte("Rar!\x1a\x07"), "application/vnd.rar", nil},
+	{257, []byte("ustar"), "application/x-tar", nil},
+	{0, []byte("\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1"), "application/x-ole-storage", nil},
+	{0, []byte("-----BEGIN "), "application/x-pem-file", nil},
+	{0, []byte("KDBX"), "application/x-keepass", nil},
+	{0, []byte("\x03\xd9\xa2\x9a"), "application/x-keepass", nil},
+}
+
+// shebangType 脚本解释器名称前缀与 MIME 类型
+type shebangType struct {
+	name string
+	mime string
+}
+
+// shebangTypes 脚本解释器与 MIME 类型的对应关系，按前缀匹配
+// 较长的名称排在前面，使重叠的前缀（如 python3 与 python）得到确定的结果
+var shebangTypes = []shebangType{
+	{"python3", "text/x-python"},
+	{"python2", "text/x-python"},
+	{"python", "text/x-python"},
+	{"bash", "text/x-shellscript"},
+	{"dash", "text/x-shellscript"},
+	{"perl", "text/x-perl"},
+	{"ruby", "text/x-ruby"},
+	{"node", "text/javascript"},
+	{"zsh", "text/x-shellscript"},
+	{"ksh", "text/x-shellscript"},
+	{"php", "text/x-php"},
+	{"lua", "text/x-lua"},
+	{"awk", "text/x-awk"},
+	{"sh", "text/x-shellscript"},
+}
+
+// DetectMIME 通过文件头魔数检测文件的 MIME 类型
+// 非普通文件返回 inode/* 类型，与 file(1) 的约定一致
+// info 为调用方已获取的文件信息（跟随符号链接时为链接目标的信息），为 nil 时使用 lstat 获取
+func DetectMIME(filePath string, info os.FileInfo) (string, error) {
+	if info == nil {
+		var err error
+		if info, err = os.Lstat(filePath); err != nil {
+			return "", err
+		}
+	}
+
+	mode := info.Mode()
+	switch {
+	case mode.IsDir():
+		return "inode/directory", nil
+	case mode&os.ModeSymlink != 0:
+		return "inode/symlink", nil
+	case mode&os.ModeNamedPipe != 0:
+		return "inode/fifo", nil
+	case mode&os.ModeSocket != 0:
+		return "inode/socket", nil
+	case mode&os.ModeCharDevice != 0:
+		return "inode/chardevice", nil
+	case mode&os.ModeDevice != 0:
+		return "inode/blockdevice", nil
+	}
+
+	if info.Size() == 0 {
+		return "inode/x-empty", nil
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	buffer := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, buffer)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+
+	return SniffMIME(buffer[:n]), nil
+}
+
+// SniffMIME 根据文件头数据判断 MIME 类型
+func SniffMIME(data []byte) string {
+	for _, sig := range magicSignatures {
+		end := sig.offset + len(sig.magic)
+		if len(data) >= end && bytes.Equal(data[sig.offset:end], sig.magic) &&
+			(sig.verify == nil || sig.verify(data)) {
+			return sig.mime
+		}
+	}
+
+	if bytes.HasPrefix(data, []byte("#!")) {
+		return shebangMIME(data)
+	}
+
+	// UTF-16 文本带有 BOM，不能按二进制处理
+	if bytes.HasPrefix(data, []byte("\xff\xfe")) || bytes.HasPrefix(data, []byte("\xfe\xff")) {
+		return "text/plain"
+	}
+
+	// 其余格式交给标准库的 WHATWG 嗅探算法
+	mime := http.DetectContentType(data)
+	if i := strings.Index(mime, ";"); i >= 0 {
+		mime = mime[:i]
+	}
+
+	// 标准库无法识别的非 UTF-8 文本（如 GBK）按控制字符比例判断
+	if mime == "application/octet-stream" && !looksBinary(data) {
+		return "text/plain"
+	}
+	return mime
+}
+
+// shebangMIME 根据 #! 行中的解释器判断脚本类型
+func shebangMIME(data []byte) string {
+	line := data[2:]
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return "text/plain"
+	}
+
+	interpreter := path.Base(fields[0])
+	// 处理 "#!/usr/bin/env python3" 的形式
+	if interpreter == "env" {
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") {
+				interpreter = path.Base(f)
+				break
+			}
+		}
+	}
+
+	// 前缀匹配同时覆盖 python3.11 等带版本号的解释器
+	for _, t := range shebangTypes {
+		if strings.HasPrefix(interpreter, t.name) {
+			return t.mime
+		}
+	}
+	return "text/x-script"
+}
+
+// isPE 校验 MZ 文件头是否为 PE 可执行文件
+// 与 debug/pe 一致：0x3C 处的小端偏移指向 "PE\x00\x00" 签名；签名超出已读取的文件头时不认为是 PE
+func isPE(data []byte) bool {
+	if len(data) < 0x40 {
+		return false
+	}
+	offset := int(binary.LittleEndian.Uint32(data[0x3c:]))
+	return offset >= 0x40 && offset <= len(data)-4 && bytes.Equal(data[offset:offset+4], []byte("PE\x00\x00"))
+}
+
+// looksBinary 检查数据中是否包含 null 字节或过多的控制字符
+func looksBinary(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	nonPrintCount := 0
+	for _, b := range data {
+		if b == 0 {
+			return true
+		}
+		if b < 32 && b != 9 && b != 10 && b != 13 && b != 12 && b != 27 {
+			nonPrintCount++
+		}
+	}
+	return float64(nonPrintCount)/float64(len(data)) > 0.3
+}
+
+// IsTextMIME 判断 MIME 类型是否可以按文本进行内容搜索
+func IsTextMIME(mime string) bool {
+	if strings.HasPrefix(mime, "text/") {
+		return true
+	}
+	switch mime {
+	case "application/json", "application/xml", "application/javascript",
+		"application/x-pem-file", "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// MatchMIME 检查 MIME 类型是否匹配任一模式，支持 "text/*" 和 "*" 通配
+func MatchMIME(mime string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		switch {
+		case pattern == "":
+			continue
+		case pattern == "*" || pattern == "*/*":
+			return true
+		case strings.HasSuffix(pattern, "/*"):
+			if strings.HasPrefix(mime, pattern[:len(pattern)-1]) {
+				return true
+			}
+		case pattern == mime:
+			return true
+		}
+	}
+	return false
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
 	"strings"
 	"unicode/utf8"
 
@@ -163,45 +162,13 @@ func (p *TextParser) detectAndConvertEncoding(data []byte) (string, error) {
 }
 
 // IsTextFile 判断文件是否为文本文件
+// 通过文件头魔数检测而非扩展名判断，可识别无扩展名脚本和改名的归档文件
 func (p *TextParser) IsTextFile(filePath string) bool {
-	ext := strings.ToLower(filepath.Ext(filePath))
-
-	// 常见文本文件扩展名
-	textExts := map[string]bool{
-		".txt":  true,
-		".log":  true,
-		".conf": true,
-		".cfg":  true,
-		".ini":  true,
-		".json": true,
-		".xml":  true,
-		".yaml": true,
-		".yml":  true,
-		".md":   true,
-		".go":   true,
-		".py":   true,
-		".js":   true,
-		".html": true,
-		".css":  true,
-		".sql":  true,
-		".sh":   true,
-		".bat":  true,
-		".ps1":  true,
-	}
-
-	// 如果有扩展名且在列表中
-	if ext != "" {
-		return textExts[ext]
-	}
-
-	// 无扩展名文件，需要进一步检测
-	file, err := os.Open(filePath)
+	mime, err := DetectMIME(filePath, nil)
 	if err != nil {
 		return false
 	}
-	defer file.Close()
-
-	return !p.isBinaryFile(file)
+	return IsTextMIME(mime)
 }
 
 // GetFileLines 获取文件的所有行
@@ -215,7 +182,8 @@ func (p *TextParser) GetFileLines(filePath string) ([]string, error) {
 		return nil, fmt.Errorf("二进制文件无法按行读取")
 	}
 
-	lines := strings.Split(content, "\n")
+	// 以换行结尾的文件最后一行之后没有内容，去掉 Split 产生的空行，避免输出不存在的上下文行
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
 	return lines, nil
 }
 
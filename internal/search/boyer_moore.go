@@ -127,15 +127,22 @@ func max(a, b int) int {
 
 // ContextSearch 带上下文的搜索
 type ContextSearch struct {
-	searcher     *BoyerMoore
-	contextLines int
+	searcher *BoyerMoore
+	before   int // 匹配行之前的上下文行数
+	after    int // 匹配行之后的上下文行数
 }
 
 // NewContextSearch 创建带上下文的搜索器
 func NewContextSearch(pattern string, caseSensitive bool, contextLines int) *ContextSearch {
+	return NewContextSearchRange(pattern, caseSensitive, contextLines, contextLines)
+}
+
+// NewContextSearchRange 创建前后上下文行数不同的搜索器
+func NewContextSearchRange(pattern string, caseSensitive bool, before, after int) *ContextSearch {
 	return &ContextSearch{
-		searcher:     NewBoyerMoore(pattern, caseSensitive),
-		contextLines: contextLines,
+		searcher: NewBoyerMoore(pattern, caseSensitive),
+		before:   before,
+		after:    after,
 	}
 }
 
@@ -146,8 +153,9 @@ func (cs *ContextSearch) SearchWithContext(lines []string) []ContextMatch {
 
 	for _, match := range matches {
 		contextMatch := ContextMatch{
-			LineMatch: match,
-			Context:   cs.getContext(lines, match.LineNumber-1),
+			LineMatch:    match,
+			Context:      cs.getContext(lines, match.LineNumber-1),
+			ContextStart: max(1, match.LineNumber-cs.before),
 		}
 		contextMatches = append(contextMatches, contextMatch)
 	}
@@ -157,8 +165,8 @@ func (cs *ContextSearch) SearchWithContext(lines []string) []ContextMatch {
 
 // getContext 获取指定行的上下文
 func (cs *ContextSearch) getContext(lines []string, lineIndex int) []string {
-	start := max(0, lineIndex-cs.contextLines)
-	end := min(len(lines), lineIndex+cs.contextLines+1)
+	start := max(0, lineIndex-cs.before)
+	end := min(len(lines), lineIndex+cs.after+1)
 
 	context := make([]string, end-start)
 	copy(context, lines[start:end])
@@ -168,8 +176,9 @@ func (cs *ContextSearch) getContext(lines []string, lineIndex int) []string {
 
 // ContextMatch 带上下文的匹配结果
 type ContextMatch struct {
-	LineMatch LineMatch
-	Context   []string
+	LineMatch    LineMatch
+	Context      []string
+	ContextStart int // 上下文第一行的行号
 }
 
 // min 返回两个整数中的较小值
@@ -0,0 +1,146 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// csvHeaders CSV/TSV 输出的列，顺序固定，新增列只能追加到末尾
+// line、column、text 为匹配行的行号、列号（从 1 开始，按字符计）和内容；
+// 逐行模式下每个匹配行一行，否则只记录第一个匹配行
+var csvHeaders = []string{
+	"time", "type", "path", "size", "mod_time", "permissions", "file_type", "mime_type",
+	"link_target", "match_type", "match_count", "line", "column", "text", "keyword",
+}
+
+// SetCsvOptions 设置 CSV 分隔符以及是否每个匹配行输出一行，需在写入第一个结果前调用
+// 分隔符对 TSV 格式无效
+func (om *OutputManager) SetCsvOptions(delimiter rune, perMatch bool) error {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+
+	if delimiter != 0 {
+		if delimiter == '"' || delimiter == '\r' || delimiter == '\n' ||
+			delimiter == utf8.RuneError || !utf8.ValidRune(delimiter) {
+			return fmt.Errorf("无效的CSV分隔符: %q", delimiter)
+		}
+		if om.csvWriter != nil {
+			om.csvWriter.Comma = delimiter
+		}
+	}
+	om.csvPerMatch = perMatch
+	return nil
+}
+
+// ParseDelimiter 解析命令行中的分隔符，支持 \t 和 tab 表示制表符
+func ParseDelimiter(s string) (rune, error) {
+	switch s {
+	case "":
+		return 0, nil
+	case `\t`, "tab":
+		return '\t', nil
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	if size != len(s) {
+		return 0, fmt.Errorf("分隔符必须是单个字符: %q", s)
+	}
+	return r, nil
+}
+
+// writeCsvHeader 写入表头，调用方需持有锁
+// 表头在写入第一个结果（或关闭）时才写入，以便在初始化输出后再设置分隔符
+func (om *OutputManager) writeCsvHeader() error {
+	if om.headerWritten {
+		return nil
+	}
+	om.headerWritten = true
+	return om.writeRecord(csvHeaders)
+}
+
+// writeCsv 以CSV或TSV格式写入，逐行模式下每个匹配行写入一行
+func (om *OutputManager) writeCsv(result *SearchResult) error {
+	if err := om.writeCsvHeader(); err != nil {
+		return err
+	}
+
+	matches := result.Matches
+	if !om.csvPerMatch && len(matches) > 1 {
+		matches = matches[:1]
+	}
+	if len(matches) == 0 {
+		return om.writeRecord(csvRecord(result, nil))
+	}
+	for i := range matches {
+		if err := om.writeRecord(csvRecord(result, &matches[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeRecord 写入一行并立即刷新，中断时已写入的行不会丢失
+func (om *OutputManager) writeRecord(record []string) error {
+	if om.outputFormat == "tsv" {
+		for i, field := range record {
+			record[i] = escapeTsv(field)
+		}
+		_, err := om.file.WriteString(strings.Join(record, "\t") + "\n")
+		return err
+	}
+
+	if err := om.csvWriter.Write(record); err != nil {
+		return err
+	}
+	om.csvWriter.Flush()
+	return om.csvWriter.Error()
+}
+
+// csvRecord 生成一行记录，大小为字节数，时间为 RFC 3339 格式
+func csvRecord(result *SearchResult, match *MatchLine) []string {
+	var line, column, text string
+	if match != nil {
+		line = strconv.Itoa(match.Line)
+		if len(match.Columns) > 0 {
+			column = strconv.Itoa(match.Columns[0])
+		}
+		text = match.Text
+	}
+
+	return []string{
+		result.Time.Format(time.RFC3339),
+		string(result.Type),
+		result.Path,
+		strconv.FormatInt(result.Size, 10),
+		isoModTime(result.ModTime),
+		result.Permissions,
+		result.FileType,
+		result.MimeType,
+		result.LinkTarget,
+		result.MatchType,
+		strconv.Itoa(result.MatchCount),
+		line,
+		column,
+		text,
+		result.Keyword,
+	}
+}
+
+// isoModTime 将 "2006-01-02 15:04:05" 格式的本地时间转换为 RFC 3339，无法解析时原样返回
+func isoModTime(modTime string) string {
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", modTime, time.Local)
+	if err != nil {
+		return modTime
+	}
+	return t.Format(time.RFC3339)
+}
+
+// tsvEscaper TSV 字段不加引号，特殊字符使用反斜杠转义
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// escapeTsv 转义 TSV 字段中的反斜杠、制表符和换行符
+func escapeTsv(field string) string {
+	return tsvEscaper.Replace(field)
+}
@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readCsv 解析 CSV 输出，返回表头之后的记录
+func readCsv(t *testing.T, data string, delimiter rune) [][]string {
+	t.Helper()
+	reader := csv.NewReader(strings.NewReader(data))
+	if delimiter != 0 {
+		reader.Comma = delimiter
+	}
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatalf("CSV 解析失败: %v\n%s", err, data)
+	}
+	if len(records) == 0 || !reflect.DeepEqual(records[0], csvHeaders) {
+		t.Fatalf("表头 = %v, want %v", records, csvHeaders)
+	}
+	return records[1:]
+}
+
+// column 返回记录中指定列的值
+func column(record []string, name string) string {
+	for i, header := range csvHeaders {
+		if header == name {
+			return record[i]
+		}
+	}
+	return ""
+}
+
+func TestWriteCsv(t *testing.T) {
+	link := &SearchResult{Type: BROKEN_LINK, Path: `C:\dir\link, "quoted"`, FileType: "l", LinkTarget: "missing"}
+
+	tests := []struct {
+		name      string
+		delimiter rune
+		perMatch  bool
+		wantLines []string // 每条记录的 line 列
+	}{
+		{"每个结果一行", 0, false, []string{"2", ""}},
+		{"每个匹配行一行", 0, true, []string{"2", "5", ""}},
+		{"分号分隔", ';', false, []string{"2", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := writeOutput(t, "csv", func(om *OutputManager) {
+				if err := om.SetCsvOptions(tt.delimiter, tt.perMatch); err != nil {
+					t.Fatal(err)
+				}
+			}, contentResult(), link)
+
+			records := readCsv(t, data, tt.delimiter)
+			if len(records) != len(tt.wantLines) {
+				t.Fatalf("记录数 = %d, want %d", len(records), len(tt.wantLines))
+			}
+			for i, want := range tt.wantLines {
+				if got := column(records[i], "line"); got != want {
+					t.Errorf("记录 %d 的 line = %q, want %q", i, got, want)
+				}
+			}
+
+			first := records[0]
+			if column(first, "column") != "1" || column(first, "text") != "flag and flag" ||
+				column(first, "size") != "42" || column(first, "match_count") != "3" {
+				t.Errorf("内容匹配记录 = %v", first)
+			}
+			if _, err := time.Parse(time.RFC3339, column(first, "mod_time")); err != nil {
+				t.Errorf("mod_time 不是 RFC 3339 格式: %q", column(first, "mod_time"))
+			}
+			if got := column(records[len(records)-1], "path"); got != link.Path {
+				t.Errorf("包含分隔符和引号的路径 = %q, want %q", got, link.Path)
+			}
+		})
+	}
+}
+
+func TestWriteTsv(t *testing.T) {
+	result := contentResult()
+	result.Path = "dir/tab\there"
+	result.Matches[0].Text = `back\slash` + "\r\nnext"
+	data := writeOutput(t, "tsv", nil, result)
+
+	lines := strings.Split(strings.TrimSuffix(data, "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("输出 %d 行, want 2:\n%s", len(lines), data)
+	}
+	if lines[0] != strings.Join(csvHeaders, "\t") {
+		t.Errorf("表头 = %q", lines[0])
+	}
+	fields := strings.Split(lines[1], "\t")
+	if len(fields) != len(csvHeaders) {
+		t.Fatalf("字段数 = %d, want %d: %q", len(fields), len(csvHeaders), lines[1])
+	}
+	if got := column(fields, "path"); got != `dir/tab\there` {
+		t.Errorf("path = %q", got)
+	}
+	if got := column(fields, "text"); got != `back\\slash\r\nnext` {
+		t.Errorf("text = %q", got)
+	}
+}
+
+func TestWriteCsvEmpty(t *testing.T) {
+	if data := writeOutput(t, "csv", nil); data != strings.Join(csvHeaders, ",")+"\n" {
+		t.Errorf("没有结果时输出 %q, want 只有表头", data)
+	}
+}
+
+func TestParseDelimiter(t *testing.T) {
+	tests := []struct {
+		in   string
+		want rune
+		ok   bool
+	}{
+		{"", 0, true},
+		{";", ';', true},
+		{`\t`, '\t', true},
+		{"tab", '\t', true},
+		{"|", '|', true},
+		{"，", '，', true},
+		{";;", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseDelimiter(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseDelimiter(%q) = %q, %v, want %q, ok=%v", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
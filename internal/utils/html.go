@@ -0,0 +1,263 @@
+package utils
+
+import (
+	"html"
+	"html/template"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// htmlReport HTML 报告的数据
+type htmlReport struct {
+	Sep     string // 路径分隔符
+	Run     RunInfo
+	Summary RunSummary
+	Types   []ResultType
+	Results []htmlResult
+}
+
+// htmlResult HTML 报告中的一行结果
+type htmlResult struct {
+	*SearchResult
+	Dir     string
+	Name    string
+	ModUnix int64
+	Matches []htmlMatch
+}
+
+// htmlMatch 一个匹配行及其上下文
+type htmlMatch struct {
+	Line  int
+	Lines []htmlLine
+}
+
+// htmlLine 上下文中的一行，关键字已高亮
+type htmlLine struct {
+	Number int
+	Text   template.HTML
+	Match  bool
+}
+
+var htmlFuncs = template.FuncMap{
+	"size": FormatFileSize,
+	"highlight": func(text, keyword string) template.HTML {
+		return highlightHTML(text, keyword)
+	},
+	"datetime": func(t time.Time) string {
+		return t.Format("2006-01-02 15:04:05")
+	},
+	"duration": func(ms int64) string {
+		return (time.Duration(ms) * time.Millisecond).String()
+	},
+}
+
+// highlightHTML 转义文本并用 <mark> 标出关键字
+func highlightHTML(text, keyword string) template.HTML {
+	return template.HTML(highlightWith(text, keyword, func(match string) string {
+		return "<mark>" + html.EscapeString(match) + "</mark>"
+	}, html.EscapeString))
+}
+
+// writeHtml 生成单文件的 HTML 报告，样式和脚本内联，不依赖外部资源，调用方需持有锁
+// 报告需要全部结果才能分组和统计，因此在关闭时一次性写入
+func (om *OutputManager) writeHtml() error {
+	report := htmlReport{
+		Sep:     string(filepath.Separator),
+		Run:     om.run,
+		Summary: om.summary(),
+		Results: make([]htmlResult, 0, len(om.htmlResults)),
+	}
+	for t := range om.fileStats {
+		report.Types = append(report.Types, t)
+	}
+	sort.Slice(report.Types, func(i, j int) bool { return report.Types[i] < report.Types[j] })
+
+	for _, result := range om.htmlResults {
+		row := htmlResult{
+			SearchResult: result,
+			Dir:          filepath.Dir(result.Path),
+			Name:         filepath.Base(result.Path),
+		}
+		if t, err := time.ParseInLocation("2006-01-02 15:04:05", result.ModTime, time.Local); err == nil {
+			row.ModUnix = t.Unix()
+		}
+		for _, match := range result.Matches {
+			row.Matches = append(row.Matches, htmlMatchOf(match, result.Keyword))
+		}
+		report.Results = append(report.Results, row)
+	}
+	sort.Slice(report.Results, func(i, j int) bool { return report.Results[i].Path < report.Results[j].Path })
+
+	return htmlTemplate.Execute(om.file, report)
+}
+
+// htmlMatchOf 转换匹配行的上下文，没有上下文时只显示匹配行
+func htmlMatchOf(match MatchLine, keyword string) htmlMatch {
+	lines := match.Context
+	start := match.ContextStart
+	if len(lines) == 0 {
+		lines = []string{match.Text}
+		start = match.Line
+	}
+
+	m := htmlMatch{Line: match.Line, Lines: make([]htmlLine, len(lines))}
+	for i, text := range lines {
+		number := start + i
+		m.Lines[i] = htmlLine{
+			Number: number,
+			Text:   highlightHTML(text, keyword),
+			Match:  number == match.Line,
+		}
+	}
+	return m
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(htmlFuncs).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>finder 搜索报告{{with .Run.Query.Keyword}} - {{.}}{{end}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", "PingFang SC", "Microsoft YaHei", sans-serif; margin: 24px; color: #222; }
+h1 { font-size: 22px; margin: 0 0 12px; }
+.summary { display: flex; flex-wrap: wrap; gap: 12px; margin-bottom: 16px; }
+.card { border: 1px solid #ddd; border-radius: 6px; padding: 8px 14px; background: #fafafa; }
+.card b { display: block; font-size: 18px; }
+.status-completed { color: #1a7f37; }
+.status-interrupted, .status-timeout { color: #bf8700; }
+.toolbar { display: flex; flex-wrap: wrap; gap: 12px; align-items: center; margin-bottom: 12px; }
+.toolbar input[type=search] { width: 320px; padding: 4px 8px; }
+table { border-collapse: collapse; width: 100%; font-size: 13px; }
+th, td { border-bottom: 1px solid #eee; padding: 6px 8px; text-align: left; vertical-align: top; }
+th { background: #f3f3f3; cursor: pointer; user-select: none; white-space: nowrap; position: sticky; top: 0; }
+th.asc::after { content: " ▲"; }
+th.desc::after { content: " ▼"; }
+td.num { text-align: right; white-space: nowrap; }
+tr.group td { background: #eef4fb; font-weight: bold; }
+.path { font-family: monospace; word-break: break-all; }
+.dir { color: #888; }
+mark { background: #ffe066; padding: 0 1px; }
+details summary { cursor: pointer; color: #0969da; }
+pre { margin: 4px 0 8px; background: #f6f8fa; padding: 6px; overflow-x: auto; font-size: 12px; }
+pre .ln { color: #999; display: inline-block; min-width: 4em; }
+pre .hit { background: #fff8c5; }
+.meta { color: #666; font-size: 12px; margin-bottom: 16px; }
+</style>
+</head>
+<body>
+<h1>finder 搜索报告</h1>
+<div class="meta">
+{{with .Run.Query.Keyword}}关键字: <b>{{.}}</b> · {{end}}模式: {{.Run.Query.Mode}} · 起始目录: {{.Run.Config.StartDir}}
+· 开始: {{datetime .Run.StartTime}} · 结束: {{datetime .Summary.EndTime}} · 耗时: {{duration .Summary.DurationMs}}
+</div>
+<div class="summary">
+<div class="card">结果<b>{{.Summary.Total}}</b></div>
+{{range $t, $n := .Summary.ByType}}<div class="card">{{$t}}<b>{{$n}}</b></div>
+{{end}}<div class="card">状态<b class="status-{{.Summary.Status}}">{{.Summary.Status}}</b></div>
+</div>
+<div class="toolbar">
+<input type="search" id="filter" placeholder="筛选路径、MIME、权限…">
+<select id="type"><option value="">全部类型</option>{{range .Types}}<option>{{.}}</option>{{end}}</select>
+<label><input type="checkbox" id="group"> 按目录分组</label>
+<span id="count"></span>
+</div>
+<table>
+<thead><tr>
+<th data-key="path">路径</th>
+<th data-key="type">类型</th>
+<th data-key="size" data-num>大小</th>
+<th data-key="mtime" data-num>修改时间</th>
+<th data-key="perm">权限</th>
+<th data-key="mime">MIME</th>
+<th data-key="matches" data-num>匹配</th>
+<th>上下文</th>
+</tr></thead>
+<tbody id="results">
+{{range .Results}}<tr class="result" data-path="{{.Path}}" data-dir="{{.Dir}}" data-type="{{.Type}}" data-size="{{.Size}}" data-mtime="{{.ModUnix}}" data-perm="{{.Permissions}}" data-mime="{{.MimeType}}" data-matches="{{.MatchCount}}">
+<td class="path"><span class="dir">{{.Dir}}{{$.Sep}}</span>{{if eq .MatchType "filename" "both"}}{{highlight .Name .Keyword}}{{else}}{{.Name}}{{end}}{{with .LinkTarget}}<br>→ {{.}}{{end}}</td>
+<td>{{.Type}}</td>
+<td class="num">{{size .Size}}</td>
+<td class="num">{{.ModTime}}</td>
+<td>{{.Permissions}}</td>
+<td>{{.MimeType}}</td>
+<td class="num">{{if .MatchCount}}{{.MatchCount}}{{end}}</td>
+<td>{{if .Matches}}<details><summary>{{len .Matches}} 行</summary>
+{{range .Matches}}<pre>{{range .Lines}}<span class="{{if .Match}}hit{{end}}"><span class="ln">{{.Number}}</span>{{.Text}}</span>
+{{end}}</pre>{{end}}</details>{{end}}</td>
+</tr>
+{{end}}</tbody>
+</table>
+<script>
+(function () {
+  var tbody = document.getElementById("results");
+  var rows = Array.prototype.slice.call(tbody.querySelectorAll("tr.result"));
+  var filter = document.getElementById("filter");
+  var type = document.getElementById("type");
+  var group = document.getElementById("group");
+  var count = document.getElementById("count");
+  var sortKey = "path", sortNum = false, sortDir = 1;
+
+  function value(row, key) {
+    var v = row.getAttribute("data-" + key) || "";
+    return sortNum ? parseFloat(v) || 0 : v.toLowerCase();
+  }
+
+  function render() {
+    var text = filter.value.toLowerCase();
+    var visible = rows.filter(function (row) {
+      if (type.value && row.getAttribute("data-type") !== type.value) return false;
+      if (!text) return true;
+      return ["path", "mime", "perm", "type"].some(function (key) {
+        return (row.getAttribute("data-" + key) || "").toLowerCase().indexOf(text) >= 0;
+      });
+    });
+    visible.sort(function (a, b) {
+      if (group.checked) {
+        var da = a.getAttribute("data-dir"), db = b.getAttribute("data-dir");
+        if (da !== db) return da < db ? -1 : 1;
+      }
+      var va = value(a, sortKey), vb = value(b, sortKey);
+      return va < vb ? -sortDir : va > vb ? sortDir : 0;
+    });
+
+    tbody.textContent = "";
+    var dir = null;
+    visible.forEach(function (row) {
+      if (group.checked && row.getAttribute("data-dir") !== dir) {
+        dir = row.getAttribute("data-dir");
+        var n = visible.filter(function (r) { return r.getAttribute("data-dir") === dir; }).length;
+        var tr = document.createElement("tr");
+        var td = document.createElement("td");
+        tr.className = "group";
+        td.colSpan = 8;
+        td.textContent = dir + " (" + n + ")";
+        tr.appendChild(td);
+        tbody.appendChild(tr);
+      }
+      tbody.appendChild(row);
+    });
+    count.textContent = "显示 " + visible.length + " / " + rows.length;
+  }
+
+  document.querySelectorAll("th[data-key]").forEach(function (th) {
+    th.addEventListener("click", function () {
+      var key = th.getAttribute("data-key");
+      sortDir = key === sortKey ? -sortDir : 1;
+      sortKey = key;
+      sortNum = th.hasAttribute("data-num");
+      document.querySelectorAll("th").forEach(function (h) { h.className = ""; });
+      th.className = sortDir > 0 ? "asc" : "desc";
+      render();
+    });
+  });
+  filter.addEventListener("input", render);
+  type.addEventListener("change", render);
+  group.addEventListener("change", render);
+  render();
+})();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// messageOut 横幅、进度和 PrintInfo 等提示信息的输出位置
+// 结果以行格式输出到标准输出时改为标准错误，保证标准输出只包含结果
+var messageOut io.Writer = os.Stdout
+
+// SetMessageOutput 设置提示信息的输出位置
+func SetMessageOutput(w io.Writer) {
+	messageOut = w
+}
+
+// IsLineFormat 是否为逐行输出的格式，这些格式在未指定输出文件时写到标准输出，代替终端表格
+func IsLineFormat(format string) bool {
+	switch format {
+	case "grep", "vimgrep", "paths", "template":
+		return true
+	}
+	return false
+}
+
+// SetNullSeparated 以 NUL 字符代替换行结束每条记录，便于 xargs -0 处理
+func (om *OutputManager) SetNullSeparated(null bool) {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	om.nullSeparated = null
+}
+
+// terminator 返回每条记录的结束符，调用方需持有锁
+func (om *OutputManager) terminator() string {
+	if om.nullSeparated {
+		return "\x00"
+	}
+	return "\n"
+}
+
+// writeLines 以 grep、vimgrep 或仅路径的格式写入
+// grep:    每个匹配行一行 path:line:col:text
+// vimgrep: 每处匹配一行 path:line:col:text，可直接用于编辑器的 quickfix 列表
+// 列号与 grep --column、rg --vimgrep 一致按字节计，与 vim quickfix 的 %c 对应
+// paths:   每个文件只输出路径
+// 没有匹配行的结果（文件名、权限、时间搜索）只输出路径，vimgrep 定位到第 1 行第 1 列
+func (om *OutputManager) writeLines(result *SearchResult) error {
+	end := om.terminator()
+	var buf strings.Builder
+
+	switch {
+	case om.outputFormat == "paths":
+		buf.WriteString(result.Path + end)
+	case len(result.Matches) == 0:
+		if om.outputFormat == "vimgrep" {
+			buf.WriteString(result.Path + ":1:1:" + end)
+		} else {
+			buf.WriteString(result.Path + end)
+		}
+	default:
+		for _, match := range result.Matches {
+			columns := match.Columns
+			if len(columns) == 0 {
+				columns = []int{1}
+			}
+			if om.outputFormat == "grep" {
+				columns = columns[:1]
+			}
+			for _, column := range columns {
+				buf.WriteString(result.Path)
+				buf.WriteByte(':')
+				buf.WriteString(strconv.Itoa(match.Line))
+				buf.WriteByte(':')
+				buf.WriteString(strconv.Itoa(byteColumn(match.Text, column)))
+				buf.WriteByte(':')
+				buf.WriteString(match.Text)
+				buf.WriteString(end)
+			}
+		}
+	}
+
+	_, err := io.WriteString(om.file, buf.String())
+	return err
+}
+
+// byteColumn 将按字符计的列号（从 1 开始）转换为按字节计的列号
+func byteColumn(text string, column int) int {
+	n := 1
+	for i := range text {
+		if n == column {
+			return i + 1
+		}
+		n++
+	}
+	return len(text) + column - n + 1
+}
@@ -7,34 +7,53 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"text/template"
 	"time"
+	"unicode/utf8"
 )
 
 // ResultType 定义结果类型
 type ResultType string
 
 const (
-	FILE_FOUND    ResultType = "FILE_FOUND" // 文件发现
-	CONTENT_MATCH ResultType = "CONTENT"    // 内容匹配
-	PERM_MATCH    ResultType = "PERMISSION" // 权限匹配
-	TIME_MATCH    ResultType = "TIME"       // 时间匹配
+	FILE_FOUND    ResultType = "FILE_FOUND"  // 文件发现
+	CONTENT_MATCH ResultType = "CONTENT"     // 内容匹配
+	PERM_MATCH    ResultType = "PERMISSION"  // 权限匹配
+	TIME_MATCH    ResultType = "TIME"        // 时间匹配
+	BROKEN_LINK   ResultType = "BROKEN_LINK" // 失效的符号链接
 )
 
 // SearchResult 搜索结果结构
 type SearchResult struct {
-	Time        time.Time              `json:"time"`        // 发现时间
-	Type        ResultType             `json:"type"`        // 结果类型
-	Path        string                 `json:"path"`        // 文件路径
-	Size        int64                  `json:"size"`        // 文件大小
-	ModTime     string                 `json:"mod_time"`    // 修改时间
-	Permissions string                 `json:"permissions"` // 权限
-	MatchType   string                 `json:"match_type"`  // 匹配类型
-	MatchCount  int                    `json:"match_count"` // 匹配次数
-	Content     string                 `json:"content"`     // 内容预览
-	Details     map[string]interface{} `json:"details"`     // 详细信息
-	Keyword     string                 `json:"keyword"`     // 匹配的关键字
+	Time        time.Time              `json:"time"`                  // 发现时间
+	Type        ResultType             `json:"type"`                  // 结果类型
+	Path        string                 `json:"path"`                  // 文件路径
+	Size        int64                  `json:"size"`                  // 文件大小
+	ModTime     string                 `json:"mod_time"`              // 修改时间
+	Permissions string                 `json:"permissions"`           // 权限
+	FileType    string                 `json:"file_type"`             // 文件类型：f, d, l, s, p, b, c
+	MimeType    string                 `json:"mime_type"`             // 内容检测得到的 MIME 类型
+	LinkTarget  string                 `json:"link_target,omitempty"` // 符号链接指向的路径
+	MatchType   string                 `json:"match_type"`            // 匹配类型
+	MatchCount  int                    `json:"match_count"`           // 匹配次数
+	Content     string                 `json:"content"`               // 内容预览
+	Details     map[string]interface{} `json:"details"`               // 详细信息
+	Keyword     string                 `json:"keyword"`               // 匹配的关键字
+	Matches     []MatchLine            `json:"matches,omitempty"`     // 内容匹配的行
+	Owner       string                 `json:"owner,omitempty"`       // 文件所有者，仅 -group-by owner 时填充
+}
+
+// MatchLine 内容匹配的单行
+type MatchLine struct {
+	Line         int      `json:"line"`                    // 行号，从 1 开始
+	Columns      []int    `json:"columns"`                 // 每处匹配的列号，从 1 开始，按字符计
+	Text         string   `json:"text"`                    // 匹配行的内容
+	Context      []string `json:"context,omitempty"`       // 匹配行及其上下文
+	ContextStart int      `json:"context_start,omitempty"` // 上下文第一行的行号
 }
 
 // OutputManager 输出管理器
@@ -44,17 +63,48 @@ type OutputManager struct {
 	outputFormat  string
 	file          *os.File
 	csvWriter     *csv.Writer
-	jsonEncoder   *json.Encoder
+	csvPerMatch   bool // CSV/TSV 每个匹配行输出一行
 	isInitialized bool
 	results       []*SearchResult // 缓存结果用于终端显示
+
+	// 文件输出的运行信息和统计，用于 JSON 的头部和末尾
+	run           RunInfo
+	status        string
+	headerWritten bool
+	fileCount     int
+	fileStats     map[ResultType]int
+	sarifRules    []string           // SARIF 中出现过的规则
+	sarifCount    int                // 已写入的 SARIF 结果数
+	htmlResults   []*SearchResult    // HTML 报告在关闭时一次性生成
+	template      *template.Template // -format template 使用的模板
+	toStdout      bool               // 未指定输出文件时逐行格式写到标准输出，代替终端表格
+	nullSeparated bool               // 以 NUL 字符结束每条记录
+	countOnly     bool               // -count 时只输出结果总数
+	total         int                // 已添加的结果数
+	writeErr      error              // 第一次写入结果失败的错误，如模板执行出错
+	groupBy       string             // -group-by 分组方式，为空时不分组
+	jsonResults   []*SearchResult    // 分组时 JSON 结果在关闭时按组嵌套写入
+
+	// 流式输出：结果到达时立即打印到终端，只保留统计和失效链接
+	stream      io.Writer
+	streamCount int
+	streamStats map[ResultType]int
+	brokenLinks []*SearchResult
 }
 
+// 流式输出时无法预先计算列宽，使用固定宽度
+const (
+	streamPathWidth = 50
+	streamSizeWidth = 8
+	streamMimeWidth = 24
+)
+
 // GlobalOutputManager 全局输出管理器实例
 var GlobalOutputManager *OutputManager
 
 // InitOutputManager 初始化输出管理器
 // outputPath: 输出文件路径
-// outputFormat: 输出格式 (txt, json, csv)
+// outputFormat: 输出格式 (txt, json, ndjson, csv, tsv, sarif, html, template, grep, vimgrep, paths)
 func InitOutputManager(outputPath, outputFormat string) error {
 	if outputFormat == "" {
 		outputFormat = "txt" // 默认格式
@@ -62,7 +112,7 @@ func InitOutputManager(outputPath, outputFormat string) error {
 
 	// 验证输出格式
 	switch outputFormat {
-	case "txt", "json", "csv":
+	case "txt", "json", "ndjson", "csv", "tsv", "sarif", "html", "template", "grep", "vimgrep", "paths":
 		// 有效的格式
 	default:
 		return fmt.Errorf("不支持的输出格式: %s", outputFormat)
@@ -82,12 +132,19 @@ func InitOutputManager(outputPath, outputFormat string) error {
 		outputPath:   outputPath,
 		outputFormat: outputFormat,
 		results:      make([]*SearchResult, 0),
+		run:          RunInfo{Tool: "finder", Version: ReportVersion, StartTime: time.Now()},
+		status:       StatusCompleted,
+		fileStats:    make(map[ResultType]int),
 	}
 
 	if outputPath != "" {
 		if err := manager.initialize(); err != nil {
 			return err
 		}
+	} else if IsLineFormat(outputFormat) {
+		manager.file = os.Stdout
+		manager.toStdout = true
+		manager.isInitialized = true
 	}
 
 	GlobalOutputManager = manager
@@ -103,7 +160,8 @@ func (om *OutputManager) initialize() error {
 		return nil
 	}
 
-	file, err := os.OpenFile(om.outputPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	// 每次运行覆盖输出文件，避免多次运行的结果拼接成无效的文档
+	file, err := os.OpenFile(om.outputPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
 	if err != nil {
 		return fmt.Errorf("打开输出文件失败: %v", err)
 	}
@@ -112,30 +170,58 @@ func (om *OutputManager) initialize() error {
 	switch om.outputFormat {
 	case "csv":
 		om.csvWriter = csv.NewWriter(file)
-		headers := []string{"Time", "Type", "Path", "Size", "ModTime", "Permissions", "MatchType", "MatchCount", "Content"}
-		if err := om.csvWriter.Write(headers); err != nil {
-			file.Close()
-			return fmt.Errorf("写入CSV头部失败: %v", err)
-		}
-		om.csvWriter.Flush()
-	case "json":
-		om.jsonEncoder = json.NewEncoder(file)
-		om.jsonEncoder.SetIndent("", "  ")
-	case "txt":
-		// TXT格式不需要特殊初始化
+	case "json", "tsv", "sarif":
+		// JSON、SARIF 文档的头部和 TSV 的表头在写入第一个结果时写入
+	case "ndjson", "txt", "html", "template", "grep", "vimgrep", "paths":
+		// NDJSON、TXT、HTML、模板和逐行格式不需要特殊初始化
 	}
 
 	om.isInitialized = true
 	return nil
 }
 
+// SetStreaming 开启流式输出，之后每个结果在 AddResult 时立即打印到 writer，不再缓存
+// PrintResults 只输出统计和失效的符号链接
+func (om *OutputManager) SetStreaming(writer io.Writer) {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	om.stream = writer
+	om.streamStats = make(map[ResultType]int)
+}
+
 // AddResult 添加搜索结果
 func (om *OutputManager) AddResult(result *SearchResult) error {
 	om.mu.Lock()
 	defer om.mu.Unlock()
 
-	// 缓存结果
-	om.results = append(om.results, result)
+	err := om.addResult(result)
+	if err != nil && om.writeErr == nil {
+		// 只提示第一次失败，退出时通过 WriteErr 返回错误码
+		om.writeErr = err
+		PrintError("写入结果失败: %v", err)
+	}
+	return err
+}
+
+// addResult 添加结果，调用方需持有锁
+func (om *OutputManager) addResult(result *SearchResult) error {
+	om.total++
+	if om.countOnly {
+		if om.isInitialized && !om.toStdout {
+			return om.writeToFile(result)
+		}
+		return nil
+	}
+	if om.toStdout {
+		return om.writeToFile(result)
+	}
+
+	if om.stream != nil {
+		om.printStreamed(result)
+	} else {
+		// 缓存结果
+		om.results = append(om.results, result)
+	}
 
 	// 如果初始化了文件输出，写入文件
 	if om.isInitialized {
@@ -147,13 +233,29 @@ func (om *OutputManager) AddResult(result *SearchResult) error {
 
 // writeToFile 写入结果到文件
 func (om *OutputManager) writeToFile(result *SearchResult) error {
+	defer func() {
+		om.fileCount++
+		om.fileStats[result.Type]++
+	}()
+
 	switch om.outputFormat {
 	case "txt":
 		return om.writeTxt(result)
 	case "json":
 		return om.writeJson(result)
-	case "csv":
+	case "ndjson":
+		return om.writeNdjson(result)
+	case "csv", "tsv":
 		return om.writeCsv(result)
+	case "sarif":
+		return om.writeSarif(result)
+	case "html":
+		om.htmlResults = append(om.htmlResults, result)
+		return nil
+	case "template":
+		return om.writeTemplate(result)
+	case "grep", "vimgrep", "paths":
+		return om.writeLines(result)
 	default:
 		return fmt.Errorf("不支持的输出格式: %s", om.outputFormat)
 	}
@@ -168,6 +270,9 @@ func (om *OutputManager) writeTxt(result *SearchResult) error {
 	if result.MatchCount > 0 {
 		details = append(details, fmt.Sprintf("匹配次数=%d", result.MatchCount))
 	}
+	if result.LinkTarget != "" {
+		details = append(details, fmt.Sprintf("链接目标=%s", result.LinkTarget))
+	}
 	if result.Content != "" && result.Content != "[二进制文件]" {
 		content := strings.ReplaceAll(result.Content, "\n", " ")
 		if len(content) > 100 {
@@ -176,11 +281,13 @@ func (om *OutputManager) writeTxt(result *SearchResult) error {
 		details = append(details, fmt.Sprintf("内容=%s", content))
 	}
 
-	txt := fmt.Sprintf("[%s] [%s] %s | 大小=%s | 修改时间=%s | 权限=%s%s\n",
+	txt := fmt.Sprintf("[%s] [%s] %s | 类型=%s | MIME=%s | 大小=%s | 修改时间=%s | 权限=%s%s\n",
 		result.Time.Format("2006-01-02 15:04:05"),
 		result.Type,
 		result.Path,
-		formatFileSize(result.Size),
+		result.FileType,
+		result.MimeType,
+		FormatFileSize(result.Size),
 		result.ModTime,
 		result.Permissions,
 		func() string {
@@ -195,33 +302,65 @@ func (om *OutputManager) writeTxt(result *SearchResult) error {
 	return err
 }
 
-// writeJson 以JSON格式写入
+// writeJson 以JSON格式写入，结果作为 results 数组的元素；分组时暂存到关闭时写入
 func (om *OutputManager) writeJson(result *SearchResult) error {
-	return om.jsonEncoder.Encode(result)
-}
-
-// writeCsv 以CSV格式写入
-func (om *OutputManager) writeCsv(result *SearchResult) error {
-	details, _ := json.Marshal(result.Details)
-
-	record := []string{
-		result.Time.Format("2006-01-02 15:04:05"),
-		string(result.Type),
-		result.Path,
-		formatFileSize(result.Size),
-		result.ModTime,
-		result.Permissions,
-		result.MatchType,
-		fmt.Sprintf("%d", result.MatchCount),
-		result.Content,
-		string(details),
+	if om.groupBy != "" {
+		om.jsonResults = append(om.jsonResults, result)
+		return nil
+	}
+	if err := om.writeJsonHeader(); err != nil {
+		return err
 	}
+	data, err := json.MarshalIndent(result, "    ", "  ")
+	if err != nil {
+		return err
+	}
+	sep := ",\n    "
+	if om.fileCount == 0 {
+		sep = "\n    "
+	}
+	_, err = fmt.Fprintf(om.file, "%s%s", sep, data)
+	return err
+}
 
-	if err := om.csvWriter.Write(record); err != nil {
+// writeNdjson 以NDJSON格式写入，每行一个紧凑的JSON对象
+func (om *OutputManager) writeNdjson(result *SearchResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
 		return err
 	}
-	om.csvWriter.Flush()
-	return om.csvWriter.Error()
+	_, err = fmt.Fprintf(om.file, "%s\n", data)
+	return err
+}
+
+// SetGroupBy 设置分组方式，终端按组输出并显示每组的数量和大小，JSON 输出按组嵌套
+// 结果需按 OrderResults 排好序后添加
+func (om *OutputManager) SetGroupBy(groupBy string) {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	om.groupBy = groupBy
+}
+
+// SetCountOnly 只统计结果数，不在终端输出结果，PrintResults 只输出总数
+// 指定了输出文件时结果仍写入文件
+func (om *OutputManager) SetCountOnly(countOnly bool) {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	om.countOnly = countOnly
+}
+
+// Total 返回已添加的结果数
+func (om *OutputManager) Total() int {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	return om.total
+}
+
+// WriteErr 返回第一次写入结果失败的错误，全部写入成功时为 nil
+func (om *OutputManager) WriteErr() error {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	return om.writeErr
 }
 
 // GetResults 获取所有缓存的结果
@@ -231,14 +370,41 @@ func (om *OutputManager) GetResults() []*SearchResult {
 	return om.results
 }
 
+// printStreamed 流式输出单个结果，失效的符号链接留到最后单独列出，调用方需持有锁
+func (om *OutputManager) printStreamed(result *SearchResult) {
+	om.streamStats[result.Type]++
+	if result.Type == BROKEN_LINK {
+		om.brokenLinks = append(om.brokenLinks, result)
+		return
+	}
+	om.streamCount++
+	om.printRow(om.stream, om.streamCount, result, streamPathWidth, streamSizeWidth, streamMimeWidth)
+}
+
 // PrintResults 打印结果到终端（列对齐格式）
-// 格式: [时间] [id][文件路径][大小][修改时间][权限][匹配内容]
+// 格式: [时间] [id][类型][文件路径][大小][修改时间][权限][MIME][匹配内容]
+// 流式输出时结果已逐条打印，这里只输出统计和失效的符号链接；逐行格式输出到标准输出时不打印
+// 统计和未找到结果的提示属于提示信息，输出到 messageOut
+// -count 时只输出结果总数
 func (om *OutputManager) PrintResults(writer io.Writer) {
 	om.mu.Lock()
 	defer om.mu.Unlock()
 
+	if om.countOnly {
+		fmt.Fprintln(writer, om.total)
+		return
+	}
+	if om.toStdout {
+		return
+	}
+
+	if om.stream != nil {
+		om.printStreamSummary(writer)
+		return
+	}
+
 	if len(om.results) == 0 {
-		fmt.Fprintln(writer, "[*] 未找到匹配的文件")
+		fmt.Fprintln(messageOut, "[*] 未找到匹配的文件")
 		return
 	}
 
@@ -248,57 +414,198 @@ func (om *OutputManager) PrintResults(writer io.Writer) {
 		stats[r.Type]++
 	}
 
-	// 打印统计信息（单行）
-	var statParts []string
-	for t, count := range stats {
-		statParts = append(statParts, fmt.Sprintf("%s:%d", t, count))
-	}
-	fmt.Fprintf(writer, "[%s] [+] 找到 %d 个结果 %s\n",
-		time.Now().Format("2006-01-02 15:04:05"),
-		len(om.results),
-		strings.Join(statParts, " "))
+	printStats(len(om.results), stats)
 
 	// 计算动态列宽
 	maxPathLen := 30 // 路径最小宽度
 	maxSizeLen := 8  // 大小最小宽度
+	maxMimeLen := 10 // MIME最小宽度
 	for _, result := range om.results {
 		pathLen := len(result.Path)
 		if pathLen > maxPathLen && pathLen < 60 {
 			maxPathLen = pathLen
 		}
-		sizeLen := len(formatFileSize(result.Size))
+		sizeLen := len(FormatFileSize(result.Size))
 		if sizeLen > maxSizeLen {
 			maxSizeLen = sizeLen
 		}
+		if len(result.MimeType) > maxMimeLen {
+			maxMimeLen = len(result.MimeType)
+		}
+	}
+
+	// 失效的符号链接单独列出
+	var results, brokenLinks []*SearchResult
+	for _, result := range om.results {
+		if result.Type == BROKEN_LINK {
+			brokenLinks = append(brokenLinks, result)
+		} else {
+			results = append(results, result)
+		}
+	}
+
+	// 分组时按组输出，失效的符号链接也在所属的组中
+	if om.groupBy != "" {
+		om.printGroups(writer, maxPathLen, maxSizeLen, maxMimeLen)
+		return
+	}
+
+	// 打印详细结果（列对齐格式，无表头），空行与统计分隔
+	fmt.Fprintln(messageOut)
+	for i, result := range results {
+		om.printRow(writer, i+1, result, maxPathLen, maxSizeLen, maxMimeLen)
+	}
+
+	printBrokenLinks(writer, brokenLinks)
+}
+
+// printGroups 按组打印结果，每组前显示分组的值、结果数和文件大小之和
+func (om *OutputManager) printGroups(writer io.Writer, pathWidth, sizeWidth, mimeWidth int) {
+	index := 0
+	for _, group := range GroupResults(om.results, om.groupBy) {
+		fmt.Fprintf(writer, "\n%s== %s ==%s %d 个结果，共 %s\n",
+			ColorCyan, group.Key, ColorReset, group.Count, FormatFileSize(group.Size))
+		for _, result := range group.Results {
+			index++
+			om.printRow(writer, index, result, pathWidth, sizeWidth, mimeWidth)
+		}
+	}
+}
+
+// printStreamSummary 流式输出结束后打印统计和失效的符号链接
+func (om *OutputManager) printStreamSummary(writer io.Writer) {
+	total := 0
+	for _, count := range om.streamStats {
+		total += count
+	}
+	if total == 0 {
+		fmt.Fprintln(messageOut, "[*] 未找到匹配的文件")
+		return
+	}
+	printBrokenLinks(writer, om.brokenLinks)
+	fmt.Fprintln(messageOut)
+	printStats(total, om.streamStats)
+}
+
+// printStats 打印统计信息（单行），与其他提示信息一样输出到 messageOut，-q 时不输出
+func printStats(total int, stats map[ResultType]int) {
+	var statParts []string
+	for t, count := range stats {
+		statParts = append(statParts, fmt.Sprintf("%s:%d", t, count))
+	}
+	fmt.Fprintf(messageOut, "[%s] [+] 找到 %d 个结果 %s\n",
+		time.Now().Format("2006-01-02 15:04:05"),
+		total,
+		strings.Join(statParts, " "))
+}
+
+// printRow 按列对齐格式打印单个结果，内容匹配的结果在下方列出匹配行和上下文
+func (om *OutputManager) printRow(writer io.Writer, index int, result *SearchResult, pathWidth, sizeWidth, mimeWidth int) {
+	// 获取内容预览（关键字附近10-20个字符）
+	preview := om.extractKeywordPreview(result.Content, result.Details)
+	if preview == "" && result.LinkTarget != "" {
+		preview = "-> " + result.LinkTarget
+	}
+	if preview == "" {
+		preview = "-"
+	}
+
+	// 对预览内容中的关键字进行高亮
+	highlightedPreview := HighlightKeyword(preview, result.Keyword)
+
+	// 对路径中的关键字进行高亮（如果是文件名匹配）
+	highlightedPath := truncateString(result.Path, pathWidth)
+	if result.MatchType == "filename" || result.MatchType == "FILENAME" {
+		highlightedPath = HighlightKeyword(highlightedPath, result.Keyword)
 	}
 
-	// 打印详细结果（列对齐格式，无表头）
-	fmt.Fprintln(writer)
-	for i, result := range om.results {
-		// 获取内容预览（关键字附近10-20个字符）
-		preview := om.extractKeywordPreview(result.Content, result.Details)
-		if preview == "" {
-			preview = "-"
+	// 列对齐输出
+	mime := result.MimeType
+	if mime == "" {
+		mime = "-"
+	}
+	fmt.Fprintf(writer, "%-4d %-1s %-*s %-*s %-20s %-10s %-*s %s\n",
+		index,
+		result.FileType,
+		pathWidth, highlightedPath,
+		sizeWidth, FormatFileSize(result.Size),
+		result.Time.Format("2006-01-02 15:04:05"),
+		result.Permissions,
+		mimeWidth, mime,
+		highlightedPreview,
+	)
+	printMatches(writer, result)
+}
+
+// printMatches 在结果行下方按 ripgrep 的格式输出匹配行和上下文
+// 匹配行为 "行号:内容"，按列号高亮关键字；上下文行为 "行号-内容"；不相邻的片段之间用 "--" 分隔
+func printMatches(writer io.Writer, result *SearchResult) {
+	if len(result.Matches) == 0 {
+		return
+	}
+
+	// 合并各匹配行的上下文，重叠的部分只输出一次
+	lines := make(map[int]string)
+	hits := make(map[int][]int)
+	for _, match := range result.Matches {
+		for i, text := range match.Context {
+			lines[match.ContextStart+i] = text
 		}
+		lines[match.Line] = match.Text
+		hits[match.Line] = match.Columns
+	}
+	numbers := make([]int, 0, len(lines))
+	for n := range lines {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
 
-		// 对预览内容中的关键字进行高亮
-		highlightedPreview := HighlightKeyword(preview, result.Keyword)
+	width := len(strconv.Itoa(numbers[len(numbers)-1]))
+	keywordLen := utf8.RuneCountInString(result.Keyword)
+	for i, n := range numbers {
+		if i > 0 && n != numbers[i-1]+1 {
+			fmt.Fprintf(writer, "     %s--%s\n", ColorGray, ColorReset)
+		}
+		if columns, ok := hits[n]; ok {
+			fmt.Fprintf(writer, "     %s%*d%s:%s\n", ColorGreen, width, n, ColorReset,
+				highlightColumns(lines[n], columns, keywordLen))
+		} else {
+			fmt.Fprintf(writer, "     %s%*d-%s%s\n", ColorGray, width, n, ColorReset, lines[n])
+		}
+	}
+}
 
-		// 对路径中的关键字进行高亮（如果是文件名匹配）
-		highlightedPath := truncateString(result.Path, maxPathLen)
-		if result.MatchType == "filename" || result.MatchType == "FILENAME" {
-			highlightedPath = HighlightKeyword(highlightedPath, result.Keyword)
+// highlightColumns 高亮从指定列（从 1 开始，按字符计）开始、长度为 length 个字符的片段
+func highlightColumns(text string, columns []int, length int) string {
+	if len(columns) == 0 || length == 0 {
+		return text
+	}
+	runes := []rune(text)
+	var result strings.Builder
+	pos := 0
+	for _, column := range columns {
+		start := column - 1
+		end := start + length
+		if start < pos || end > len(runes) {
+			continue
 		}
+		result.WriteString(string(runes[pos:start]))
+		result.WriteString(ColorRed + ColorBold)
+		result.WriteString(string(runes[start:end]))
+		result.WriteString(ColorReset)
+		pos = end
+	}
+	result.WriteString(string(runes[pos:]))
+	return result.String()
+}
 
-		// 列对齐输出 - 动态宽度
-		fmt.Fprintf(writer, "%-4d %-*s %-*s %-20s %-10s %s\n",
-			i+1,
-			maxPathLen, highlightedPath,
-			maxSizeLen, formatFileSize(result.Size),
-			result.Time.Format("2006-01-02 15:04:05"),
-			result.Permissions,
-			highlightedPreview,
-		)
+// printBrokenLinks 单独列出失效的符号链接
+func printBrokenLinks(writer io.Writer, brokenLinks []*SearchResult) {
+	if len(brokenLinks) > 0 {
+		fmt.Fprintf(writer, "\n%s[!] 失效的符号链接 (%d):%s\n", ColorYellow, len(brokenLinks), ColorReset)
+		for _, result := range brokenLinks {
+			fmt.Fprintf(writer, "     %s -> %s\n", result.Path, result.LinkTarget)
+		}
 	}
 }
 
@@ -354,44 +661,83 @@ func truncateString(s string, maxLen int) string {
 
 // HighlightKeyword 高亮显示关键字（绿色）
 func HighlightKeyword(text, keyword string) string {
+	return highlightWith(text, keyword, func(match string) string {
+		return ColorGreen + match + ColorReset
+	}, func(plain string) string {
+		return plain
+	})
+}
+
+// highlightWith 不区分大小写地查找关键字，匹配部分用 mark 处理，其余部分用 plain 处理
+func highlightWith(text, keyword string, mark, plain func(string) string) string {
 	if keyword == "" {
-		return text
+		return plain(text)
 	}
-	// 不区分大小写的高亮
-	lowerText := strings.ToLower(text)
-	lowerKeyword := strings.ToLower(keyword)
 
 	var result strings.Builder
 	start := 0
 	for {
-		idx := strings.Index(lowerText[start:], lowerKeyword)
+		idx, end := indexFold(text[start:], keyword)
 		if idx == -1 {
-			result.WriteString(text[start:])
+			result.WriteString(plain(text[start:]))
 			break
 		}
-		idx += start
 		// 写入关键字前的文本
-		result.WriteString(text[start:idx])
+		result.WriteString(plain(text[start : start+idx]))
 		// 写入高亮的关键字
-		result.WriteString(ColorGreen)
-		result.WriteString(text[idx : idx+len(keyword)])
-		result.WriteString(ColorReset)
-		start = idx + len(keyword)
+		result.WriteString(mark(text[start+idx : start+end]))
+		start += end
 	}
 	return result.String()
 }
 
+// indexFold 不区分大小写地查找 substr，返回匹配部分在 s 中的起止字节位置，未找到时返回 -1
+// 逐个字符在原文上比较，不使用 strings.ToLower 后的位置，因为部分字符转换大小写后字节长度会变化（如 Ⱥ、İ）
+func indexFold(s, substr string) (int, int) {
+	n := utf8.RuneCountInString(substr)
+	for i := range s {
+		end := i
+		for k := 0; k < n && end < len(s); k++ {
+			_, size := utf8.DecodeRuneInString(s[end:])
+			end += size
+		}
+		if strings.EqualFold(s[i:end], substr) {
+			return i, end
+		}
+	}
+	return -1, -1
+}
+
 // Close 关闭输出管理器
 func (om *OutputManager) Close() error {
 	om.mu.Lock()
 	defer om.mu.Unlock()
 
-	if !om.isInitialized || om.file == nil {
+	if !om.isInitialized || om.file == nil || om.toStdout {
 		return nil
 	}
 
-	if om.csvWriter != nil {
-		om.csvWriter.Flush()
+	switch om.outputFormat {
+	case "csv", "tsv":
+		if err := om.writeCsvHeader(); err != nil {
+			om.file.Close()
+			return fmt.Errorf("写入表头失败: %v", err)
+		}
+	case "json":
+		if err := om.writeJsonFooter(); err != nil {
+			om.file.Close()
+			return fmt.Errorf("写入JSON结尾失败: %v", err)
+		}
+	case "sarif":
+		if err := om.writeSarifFooter(); err != nil {
+			om.file.Close()
+			return fmt.Errorf("写入SARIF结尾失败: %v", err)
+		}
+	case "html":
+		if err := om.writeHtml(); err != nil {
+			om.file.Close()
+			return fmt.Errorf("生成HTML报告失败: %v", err)
+		}
 	}
 
 	if err := om.file.Close(); err != nil {
@@ -402,8 +748,8 @@ func (om *OutputManager) Close() error {
 	return nil
 }
 
-// formatFileSize 格式化文件大小
-func formatFileSize(size int64) string {
+// FormatFileSize 格式化文件大小，如 1.5 MB
+func FormatFileSize(size int64) string {
 	const unit = 1024
 	if size < unit {
 		return fmt.Sprintf("%d B", size)
@@ -427,6 +773,7 @@ const (
 	ColorCyan    = "\033[36m"
 	ColorWhite   = "\033[37m"
 	ColorBold    = "\033[1m"
+	ColorGray    = "\033[90m"
 )
 
 // PrintBanner 打印程序艺术字横幅
@@ -441,7 +788,7 @@ func PrintBanner() {
                                                   
                       v2.1 - File Finder Tool
 `
-	fmt.Println(ColorCyan + banner + ColorReset)
+	fmt.Fprintln(messageOut, ColorCyan+banner+ColorReset)
 }
 
 // PrintSimpleBanner 打印简化版横幅（用于帮助信息）
@@ -453,19 +800,19 @@ func PrintSimpleBanner() {
 // PrintInfo 打印信息级别日志（带时间戳，蓝色）
 func PrintInfo(format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
-	fmt.Printf("%s[%s] [*] %s%s\n", ColorBlue, time.Now().Format("2006-01-02 15:04:05"), msg, ColorReset)
+	fmt.Fprintf(messageOut, "%s[%s] [*] %s%s\n", ColorBlue, time.Now().Format("2006-01-02 15:04:05"), msg, ColorReset)
 }
 
 // PrintSuccess 打印成功级别日志（带时间戳，绿色）
 func PrintSuccess(format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
-	fmt.Printf("%s[%s] [+] %s%s\n", ColorGreen, time.Now().Format("2006-01-02 15:04:05"), msg, ColorReset)
+	fmt.Fprintf(messageOut, "%s[%s] [+] %s%s\n", ColorGreen, time.Now().Format("2006-01-02 15:04:05"), msg, ColorReset)
 }
 
 // PrintWarning 打印警告级别日志（带时间戳，黄色）
 func PrintWarning(format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
-	fmt.Printf("%s[%s] [!] %s%s\n", ColorYellow, time.Now().Format("2006-01-02 15:04:05"), msg, ColorReset)
+	fmt.Fprintf(messageOut, "%s[%s] [!] %s%s\n", ColorYellow, time.Now().Format("2006-01-02 15:04:05"), msg, ColorReset)
 }
 
 // PrintError 打印错误级别日志（带时间戳，红色）
@@ -477,5 +824,5 @@ func PrintError(format string, args ...interface{}) {
 // PrintDebug 打印调试级别日志（带时间戳，洋红色）
 func PrintDebug(format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
-	fmt.Printf("%s[%s] [DEBUG] %s%s\n", ColorMagenta, time.Now().Format("2006-01-02 15:04:05"), msg, ColorReset)
+	fmt.Fprintf(messageOut, "%s[%s] [DEBUG] %s%s\n", ColorMagenta, time.Now().Format("2006-01-02 15:04:05"), msg, ColorReset)
 }
@@ -3,6 +3,7 @@ package utils
 import (
 	"fmt"
 	"path/filepath"
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -12,6 +13,7 @@ type ProgressBar struct {
 	current    int64
 	currentDir string
 	lastDir    string
+	mu         sync.Mutex
 }
 
 func NewProgressBar(width int) *ProgressBar {
@@ -19,15 +21,17 @@ func NewProgressBar(width int) *ProgressBar {
 }
 
 func (p *ProgressBar) SetCurrentDir(dir string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	volume := filepath.VolumeName(dir)
 	if volume != "" && volume != p.lastDir {
-		fmt.Printf("[%s] [*] 正在构建 %s 的文件索引...\n", time.Now().Format("2006-01-02 15:04:05"), volume)
+		fmt.Fprintf(messageOut, "[%s] [*] 正在构建 %s 的文件索引...\n", time.Now().Format("2006-01-02 15:04:05"), volume)
 		p.lastDir = volume
 	}
 }
 
 func (p *ProgressBar) Start() {
-	fmt.Printf("[%s] [*] 开始构建文件索引...\n", time.Now().Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(messageOut, "[%s] [*] 开始构建文件索引...\n", time.Now().Format("2006-01-02 15:04:05"))
 }
 
 func (p *ProgressBar) Increment() {
@@ -40,6 +44,6 @@ func (p *ProgressBar) SetTotal(total int64) {
 
 func (p *ProgressBar) Stop(completed bool) {
 	if completed {
-		fmt.Printf("[%s] [+] 索引构建完成\n", time.Now().Format("2006-01-02 15:04:05"))
+		fmt.Fprintf(messageOut, "[%s] [+] 索引构建完成\n", time.Now().Format("2006-01-02 15:04:05"))
 	}
 }
@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ReportVersion JSON 输出格式的版本，结构变化时递增，对应 schema/finder-output.schema.json
+const ReportVersion = 1
+
+// 运行结束状态
+const (
+	StatusCompleted   = "completed"   // 正常完成
+	StatusInterrupted = "interrupted" // 被 Ctrl-C 中断
+	StatusTimeout     = "timeout"     // 达到 -timeout
+)
+
+// RunQuery 本次运行的搜索条件
+type RunQuery struct {
+	Keyword       string `json:"keyword,omitempty"`        // 搜索关键字
+	Mode          string `json:"mode"`                     // 搜索模式：filename, content, both
+	CaseSensitive bool   `json:"case_sensitive"`           // 是否区分大小写
+	Permission    string `json:"permission,omitempty"`     // 权限位条件：r, w, rw
+	Readable      bool   `json:"readable,omitempty"`       // 仅当前用户可读
+	Writable      bool   `json:"writable,omitempty"`       // 仅当前用户可写
+	Executable    bool   `json:"executable,omitempty"`     // 仅当前用户可执行
+	ModifiedAfter string `json:"modified_after,omitempty"` // 修改时间条件 (2006-01-02)
+}
+
+// RunConfig 本次运行影响结果范围的配置
+type RunConfig struct {
+	StartDir       string   `json:"start_dir"`                 // 起始目录
+	Global         bool     `json:"global"`                    // 是否全局搜索
+	MaxDepth       int      `json:"max_depth"`                 // 最大深度，-1 表示不限制
+	Extensions     []string `json:"extensions,omitempty"`      // 扩展名过滤
+	EntryTypes     []string `json:"entry_types,omitempty"`     // 文件类型过滤
+	MimeTypes      []string `json:"mime_types,omitempty"`      // MIME 类型过滤
+	Exclude        []string `json:"exclude,omitempty"`         // 排除模式
+	Include        []string `json:"include,omitempty"`         // 包含模式
+	Hidden         string   `json:"hidden"`                    // 隐藏文件策略
+	FollowLinks    bool     `json:"follow_links"`              // 是否跟随符号链接
+	SizeLimit      int64    `json:"size_limit"`                // 文件大小限制，-1 表示不限制
+	MaxContentSize int64    `json:"max_content_size"`          // 内容搜索的最大文件大小
+	MaxResults     int      `json:"max_results,omitempty"`     // 最多返回的结果数
+	Timeout        string   `json:"timeout,omitempty"`         // 搜索超时时间
+	NoIgnore       bool     `json:"no_ignore,omitempty"`       // 是否忽略 .gitignore 等规则
+	IgnoreFiles    []string `json:"ignore_files,omitempty"`    // 额外的忽略文件
+	SameFilesystem bool     `json:"same_filesystem,omitempty"` // -xdev
+}
+
+// RunInfo JSON 输出的运行头部
+type RunInfo struct {
+	Tool      string    `json:"tool"`
+	Version   int       `json:"version"`
+	Query     RunQuery  `json:"query"`
+	Config    RunConfig `json:"config"`
+	StartTime time.Time `json:"start_time"`
+}
+
+// RunSummary JSON 输出末尾的运行统计
+type RunSummary struct {
+	EndTime    time.Time          `json:"end_time"`
+	DurationMs int64              `json:"duration_ms"`
+	Status     string             `json:"status"`
+	Total      int                `json:"total"`
+	ByType     map[ResultType]int `json:"by_type"`
+}
+
+// SetRunInfo 设置本次运行的搜索条件和配置，需在写入第一个结果前调用
+func (om *OutputManager) SetRunInfo(query RunQuery, config RunConfig) {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	om.run.Query = query
+	om.run.Config = config
+}
+
+// SetStatus 设置运行结束状态，默认为 completed
+func (om *OutputManager) SetStatus(status string) {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	om.status = status
+}
+
+// writeJsonHeader 写入 JSON 文档的开头和运行头部，调用方需持有锁
+// 分组时结果以 groups 数组代替 results 数组
+// 头部在写入第一个结果（或关闭）时才写入，以便在初始化输出后再设置运行信息
+func (om *OutputManager) writeJsonHeader() error {
+	if om.headerWritten {
+		return nil
+	}
+	om.headerWritten = true
+
+	run, err := json.MarshalIndent(om.run, "  ", "  ")
+	if err != nil {
+		return err
+	}
+	if om.groupBy != "" {
+		_, err = fmt.Fprintf(om.file, "{\n  \"run\": %s,\n  \"group_by\": %q,\n  \"groups\": ", run, om.groupBy)
+		return err
+	}
+	_, err = fmt.Fprintf(om.file, "{\n  \"run\": %s,\n  \"results\": [", run)
+	return err
+}
+
+// summary 生成写入文件的结果的运行统计，调用方需持有锁
+func (om *OutputManager) summary() RunSummary {
+	end := time.Now()
+	return RunSummary{
+		EndTime:    end,
+		DurationMs: end.Sub(om.run.StartTime).Milliseconds(),
+		Status:     om.status,
+		Total:      om.fileCount,
+		ByType:     om.fileStats,
+	}
+}
+
+// writeJsonFooter 结束结果数组并写入运行统计，调用方需持有锁
+func (om *OutputManager) writeJsonFooter() error {
+	if err := om.writeJsonHeader(); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(om.summary(), "  ", "  ")
+	if err != nil {
+		return err
+	}
+
+	// 分组时结果按组嵌套，一次性写入
+	if om.groupBy != "" {
+		groups := GroupResults(om.jsonResults, om.groupBy)
+		if groups == nil {
+			groups = []ResultGroup{}
+		}
+		nested, err := json.MarshalIndent(groups, "  ", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(om.file, "%s,\n  \"summary\": %s\n}\n", nested, data)
+		return err
+	}
+
+	closing := "\n  ],\n"
+	if om.fileCount == 0 {
+		closing = "],\n"
+	}
+	_, err = fmt.Fprintf(om.file, "%s  \"summary\": %s\n}\n", closing, data)
+	return err
+}
@@ -0,0 +1,259 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// SARIF 2.1.0 日志的固定字段
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+	sarifSrcRoot = "SRCROOT" // 相对路径的基准目录，对应运行时的工作目录
+)
+
+type sarifResult struct {
+	RuleID     string                 `json:"ruleId"`
+	Level      string                 `json:"level"`
+	Message    sarifMessage           `json:"message"`
+	Locations  []sarifLocation        `json:"locations"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+	ContextRegion    *sarifRegion          `json:"contextRegion,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int           `json:"startLine"`
+	StartColumn int           `json:"startColumn,omitempty"`
+	EndLine     int           `json:"endLine,omitempty"`
+	EndColumn   int           `json:"endColumn,omitempty"`
+	Snippet     *sarifMessage `json:"snippet,omitempty"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+// writeSarifHeader 写入 SARIF 日志的开头，调用方需持有锁
+// 规则列表在结果写完后才能确定，因此 tool 放在 results 之后写入
+func (om *OutputManager) writeSarifHeader() error {
+	if om.headerWritten {
+		return nil
+	}
+	om.headerWritten = true
+	_, err := fmt.Fprintf(om.file, "{\n  \"$schema\": %q,\n  \"version\": %q,\n  \"runs\": [\n    {\n      \"results\": [",
+		sarifSchema, sarifVersion)
+	return err
+}
+
+// writeSarif 以SARIF格式写入，内容匹配的每处匹配对应一个结果，其他结果只定位到文件
+func (om *OutputManager) writeSarif(result *SearchResult) error {
+	if err := om.writeSarifHeader(); err != nil {
+		return err
+	}
+
+	ruleID := result.Keyword
+	if ruleID == "" {
+		ruleID = string(result.Type)
+	}
+	om.addSarifRule(ruleID)
+
+	for _, r := range sarifResults(result, ruleID) {
+		data, err := json.MarshalIndent(r, "        ", "  ")
+		if err != nil {
+			return err
+		}
+		sep := ",\n        "
+		if om.sarifCount == 0 {
+			sep = "\n        "
+		}
+		if _, err := fmt.Fprintf(om.file, "%s%s", sep, data); err != nil {
+			return err
+		}
+		om.sarifCount++
+	}
+	return nil
+}
+
+// addSarifRule 记录出现过的规则，调用方需持有锁
+func (om *OutputManager) addSarifRule(id string) {
+	for _, rule := range om.sarifRules {
+		if rule == id {
+			return
+		}
+	}
+	om.sarifRules = append(om.sarifRules, id)
+}
+
+// sarifResults 将搜索结果转换为 SARIF 结果
+func sarifResults(result *SearchResult, ruleID string) []sarifResult {
+	artifact := sarifArtifact(result.Path)
+	properties := map[string]interface{}{
+		"type":        result.Type,
+		"fileType":    result.FileType,
+		"mimeType":    result.MimeType,
+		"permissions": result.Permissions,
+	}
+
+	if len(result.Matches) == 0 {
+		return []sarifResult{{
+			RuleID:     ruleID,
+			Level:      "note",
+			Message:    sarifMessage{Text: sarifMessageOf(result)},
+			Locations:  []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: artifact}}},
+			Properties: properties,
+		}}
+	}
+
+	keywordLen := utf8.RuneCountInString(result.Keyword)
+	results := make([]sarifResult, 0, len(result.Matches))
+	for _, match := range result.Matches {
+		var contextRegion *sarifRegion
+		if len(match.Context) > 0 {
+			contextRegion = &sarifRegion{
+				StartLine: match.ContextStart,
+				EndLine:   match.ContextStart + len(match.Context) - 1,
+				Snippet:   &sarifMessage{Text: strings.Join(match.Context, "\n")},
+			}
+		}
+
+		// 同一行的每处匹配各对应一个结果，没有列号时只定位到行
+		columns := match.Columns
+		if len(columns) == 0 {
+			columns = []int{0}
+		}
+		for _, column := range columns {
+			location := sarifPhysicalLocation{
+				ArtifactLocation: artifact,
+				Region: &sarifRegion{
+					StartLine: match.Line,
+					Snippet:   &sarifMessage{Text: match.Text},
+				},
+				ContextRegion: contextRegion,
+			}
+			if column > 0 {
+				location.Region.StartColumn = column
+				location.Region.EndColumn = column + keywordLen
+			}
+
+			results = append(results, sarifResult{
+				RuleID:     ruleID,
+				Level:      "warning",
+				Message:    sarifMessage{Text: sarifMatchMessage(match.Line, column, result.Keyword)},
+				Locations:  []sarifLocation{{PhysicalLocation: location}},
+				Properties: properties,
+			})
+		}
+	}
+	return results
+}
+
+// sarifMatchMessage 生成内容匹配结果的说明
+func sarifMatchMessage(line, column int, keyword string) string {
+	if column > 0 {
+		return fmt.Sprintf("第 %d 行第 %d 列匹配关键字 %q", line, column, keyword)
+	}
+	return fmt.Sprintf("第 %d 行匹配关键字 %q", line, keyword)
+}
+
+// sarifMessageOf 生成只定位到文件的结果的说明
+func sarifMessageOf(result *SearchResult) string {
+	switch result.Type {
+	case PERM_MATCH:
+		return fmt.Sprintf("权限匹配: %s", result.Permissions)
+	case TIME_MATCH:
+		return fmt.Sprintf("修改时间: %s", result.ModTime)
+	case BROKEN_LINK:
+		return fmt.Sprintf("失效的符号链接 -> %s", result.LinkTarget)
+	}
+	if result.Keyword == "" {
+		return "找到文件"
+	}
+	return fmt.Sprintf("文件名匹配关键字 %q", result.Keyword)
+}
+
+// sarifArtifact 将路径转换为 URI，相对路径基于 SRCROOT
+func sarifArtifact(path string) sarifArtifactLocation {
+	slashPath := filepath.ToSlash(path)
+	if filepath.IsAbs(path) {
+		if !strings.HasPrefix(slashPath, "/") {
+			slashPath = "/" + slashPath // Windows 盘符路径
+		}
+		return sarifArtifactLocation{URI: (&url.URL{Scheme: "file", Path: slashPath}).String()}
+	}
+	return sarifArtifactLocation{
+		URI:       (&url.URL{Path: strings.TrimPrefix(slashPath, "./")}).String(),
+		URIBaseID: sarifSrcRoot,
+	}
+}
+
+// writeSarifFooter 结束结果数组并写入工具信息、规则和运行信息，调用方需持有锁
+func (om *OutputManager) writeSarifFooter() error {
+	if err := om.writeSarifHeader(); err != nil {
+		return err
+	}
+
+	rules := make([]sarifRule, len(om.sarifRules))
+	for i, id := range om.sarifRules {
+		rules[i] = sarifRule{ID: id, ShortDescription: sarifMessage{Text: fmt.Sprintf("匹配 %q", id)}}
+	}
+
+	run := map[string]interface{}{
+		"tool": map[string]interface{}{
+			"driver": map[string]interface{}{
+				"name":  om.run.Tool,
+				"rules": rules,
+			},
+		},
+		"invocations": []map[string]interface{}{{
+			"executionSuccessful": om.status == StatusCompleted,
+			"startTimeUtc":        om.run.StartTime.UTC(),
+			"endTimeUtc":          time.Now().UTC(),
+			"properties":          map[string]interface{}{"status": om.status},
+		}},
+		"columnKind": "unicodeCodePoints",
+	}
+	if wd, err := os.Getwd(); err == nil {
+		run["originalUriBaseIds"] = map[string]interface{}{
+			sarifSrcRoot: sarifArtifactLocation{URI: strings.TrimSuffix(sarifArtifact(wd).URI, "/") + "/"},
+		}
+	}
+
+	data, err := json.MarshalIndent(run, "    ", "  ")
+	if err != nil {
+		return err
+	}
+	// 去掉外层的大括号，与 results 合并为同一个对象
+	body := strings.TrimSuffix(strings.TrimPrefix(string(data), "{"), "}")
+
+	closing := "\n      ],"
+	if om.sarifCount == 0 {
+		closing = "],"
+	}
+	_, err = fmt.Fprintf(om.file, "%s%s}\n  ]\n}\n", closing, body)
+	return err
+}
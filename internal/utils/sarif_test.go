@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeOutput 以指定格式将结果写入临时文件，返回文件内容
+// setup 在写入结果前调用，用于设置 CSV 选项等
+func writeOutput(t *testing.T, format string, setup func(om *OutputManager), results ...*SearchResult) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "out."+format)
+	if err := InitOutputManager(path, format); err != nil {
+		t.Fatalf("InitOutputManager: %v", err)
+	}
+	om := GlobalOutputManager
+	if setup != nil {
+		setup(om)
+	}
+	for _, r := range results {
+		if err := om.AddResult(r); err != nil {
+			t.Fatalf("AddResult: %v", err)
+		}
+	}
+	if err := om.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// contentResult 一个内容匹配结果：第 2 行有两处匹配，第 5 行有一处
+func contentResult() *SearchResult {
+	return &SearchResult{
+		Time: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Type: CONTENT_MATCH, Path: "src/a.txt",
+		Size: 42, ModTime: "2024-01-02 03:04:05", Permissions: "-rw-r--r--", FileType: "f",
+		MimeType: "text/plain", MatchType: "content", MatchCount: 3, Keyword: "flag",
+		Matches: []MatchLine{
+			{Line: 2, Columns: []int{1, 10}, Text: "flag and flag", Context: []string{"one", "flag and flag", "three"}, ContextStart: 1},
+			{Line: 5, Columns: []int{3}, Text: "a flag", Context: []string{"a flag"}, ContextStart: 5},
+		},
+	}
+}
+
+func TestWriteSarif(t *testing.T) {
+	perm := &SearchResult{Type: PERM_MATCH, Path: "/usr/bin/su", Permissions: "-rwsr-xr-x", FileType: "f"}
+	data := writeOutput(t, "sarif", nil, contentResult(), perm)
+
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Results []sarifResult `json:"results"`
+			Tool    struct {
+				Driver struct {
+					Rules []sarifRule `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal([]byte(data), &log); err != nil {
+		t.Fatalf("SARIF 不是有效的 JSON: %v\n%s", err, data)
+	}
+	if log.Version != sarifVersion || len(log.Runs) != 1 {
+		t.Fatalf("version = %q, runs = %d", log.Version, len(log.Runs))
+	}
+
+	results := log.Runs[0].Results
+	// 每处内容匹配一个结果，加上权限匹配的一个结果
+	if len(results) != 4 {
+		t.Fatalf("结果数 = %d, want 4", len(results))
+	}
+	wantRegions := []sarifRegion{
+		{StartLine: 2, StartColumn: 1, EndColumn: 5},
+		{StartLine: 2, StartColumn: 10, EndColumn: 14},
+		{StartLine: 5, StartColumn: 3, EndColumn: 7},
+	}
+	for i, want := range wantRegions {
+		loc := results[i].Locations[0].PhysicalLocation
+		got := *loc.Region
+		got.Snippet = nil
+		if got != want {
+			t.Errorf("结果 %d 的 region = %+v, want %+v", i, got, want)
+		}
+		if loc.ArtifactLocation.URI != "src/a.txt" || loc.ArtifactLocation.URIBaseID != sarifSrcRoot {
+			t.Errorf("结果 %d 的 artifactLocation = %+v", i, loc.ArtifactLocation)
+		}
+		if results[i].RuleID != "flag" || results[i].Level != "warning" {
+			t.Errorf("结果 %d 的 ruleId = %q, level = %q", i, results[i].RuleID, results[i].Level)
+		}
+	}
+	if ctx := results[0].Locations[0].PhysicalLocation.ContextRegion; ctx == nil || ctx.StartLine != 1 || ctx.EndLine != 3 {
+		t.Errorf("第一个结果的 contextRegion = %+v, want 1-3 行", ctx)
+	}
+
+	last := results[3]
+	if last.RuleID != string(PERM_MATCH) || last.Level != "note" || last.Locations[0].PhysicalLocation.Region != nil {
+		t.Errorf("权限匹配结果 = %+v", last)
+	}
+	if uri := last.Locations[0].PhysicalLocation.ArtifactLocation.URI; uri != "file:///usr/bin/su" {
+		t.Errorf("绝对路径的 URI = %q, want file:///usr/bin/su", uri)
+	}
+
+	if rules := log.Runs[0].Tool.Driver.Rules; len(rules) != 2 || rules[0].ID != "flag" || rules[1].ID != string(PERM_MATCH) {
+		t.Errorf("rules = %+v", rules)
+	}
+}
+
+func TestWriteSarifEmpty(t *testing.T) {
+	var log map[string]interface{}
+	if data := writeOutput(t, "sarif", nil); json.Unmarshal([]byte(data), &log) != nil {
+		t.Fatalf("没有结果时 SARIF 不是有效的 JSON:\n%s", data)
+	}
+}
@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 可用的排序字段和分组方式
+var (
+	sortKeys  = []string{"path", "size", "mtime", "matches", "score"}
+	groupKeys = []string{"dir", "ext", "type", "owner"}
+)
+
+// ResultGroup 按 -group-by 分组后的一组结果
+type ResultGroup struct {
+	Key     string          `json:"key"`     // 分组的值，如目录、扩展名
+	Count   int             `json:"count"`   // 组内结果数
+	Size    int64           `json:"size"`    // 组内文件大小之和
+	Results []*SearchResult `json:"results"` // 组内结果，按排序字段排列
+}
+
+// ValidateOrder 检查排序字段和分组方式
+func ValidateOrder(sortKey, groupBy string) error {
+	if sortKey != "" && !containsString(sortKeys, sortKey) {
+		return fmt.Errorf("不支持的排序字段: %s (可选: %s)", sortKey, strings.Join(sortKeys, ","))
+	}
+	if groupBy != "" && !containsString(groupKeys, groupBy) {
+		return fmt.Errorf("不支持的分组方式: %s (可选: %s)", groupBy, strings.Join(groupKeys, ","))
+	}
+	return nil
+}
+
+// OrderResults 按排序字段排序，指定分组时先按分组的值排列，组内保持排序顺序
+// path 按路径升序，size、mtime、matches 按从小到大，score 按相关度从高到低；reverse 时反转
+// 值相同的结果按路径排列，保证每次运行的输出顺序一致
+func OrderResults(results []*SearchResult, sortKey string, reverse bool, groupBy string) {
+	if sortKey == "" {
+		sortKey = "path"
+	}
+	less := func(a, b *SearchResult) bool {
+		switch sortKey {
+		case "size":
+			if a.Size != b.Size {
+				return a.Size < b.Size
+			}
+		case "mtime":
+			// 修改时间为 2006-01-02 15:04:05 格式，可直接按字符串比较
+			if a.ModTime != b.ModTime {
+				return a.ModTime < b.ModTime
+			}
+		case "matches":
+			if a.MatchCount != b.MatchCount {
+				return a.MatchCount < b.MatchCount
+			}
+		case "score":
+			if sa, sb := resultScore(a), resultScore(b); sa != sb {
+				return sa > sb
+			}
+		}
+		return a.Path < b.Path
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if groupBy != "" {
+			if ka, kb := GroupKey(a, groupBy), GroupKey(b, groupBy); ka != kb {
+				return ka < kb
+			}
+		}
+		if reverse {
+			return less(b, a)
+		}
+		return less(a, b)
+	})
+}
+
+// GroupResults 将已排序的结果按分组的值划分，相邻且值相同的结果为一组
+func GroupResults(results []*SearchResult, groupBy string) []ResultGroup {
+	var groups []ResultGroup
+	for _, result := range results {
+		key := GroupKey(result, groupBy)
+		if len(groups) == 0 || groups[len(groups)-1].Key != key {
+			groups = append(groups, ResultGroup{Key: key})
+		}
+		group := &groups[len(groups)-1]
+		group.Count++
+		group.Size += result.Size
+		group.Results = append(group.Results, result)
+	}
+	return groups
+}
+
+// GroupKey 返回结果在指定分组方式下的值
+func GroupKey(result *SearchResult, groupBy string) string {
+	switch groupBy {
+	case "dir":
+		return filepath.Dir(result.Path)
+	case "ext":
+		if ext := strings.ToLower(filepath.Ext(result.Path)); ext != "" {
+			return ext
+		}
+		return "(无扩展名)"
+	case "type":
+		return result.FileType
+	case "owner":
+		if result.Owner != "" {
+			return result.Owner
+		}
+		return "(未知)"
+	}
+	return ""
+}
+
+// resultScore 计算结果与关键字的相关度
+// 文件名与关键字相同得 100 分，以关键字开头得 50 分，包含关键字得 20 分，每处内容匹配再加 1 分
+func resultScore(result *SearchResult) int {
+	score := result.MatchCount
+	if result.Keyword == "" {
+		return score
+	}
+	name := strings.ToLower(filepath.Base(result.Path))
+	keyword := strings.ToLower(result.Keyword)
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+	switch {
+	case name == keyword || stem == keyword:
+		score += 100
+	case strings.HasPrefix(name, keyword):
+		score += 50
+	case strings.Contains(name, keyword):
+		score += 20
+	}
+	return score
+}
+
+// containsString 判断切片中是否包含指定字符串
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+// resultPaths 返回结果的路径列表
+func resultPaths(results []*SearchResult) []string {
+	paths := make([]string, len(results))
+	for i, r := range results {
+		paths[i] = r.Path
+	}
+	return paths
+}
+
+func sortFixture() []*SearchResult {
+	return []*SearchResult{
+		{Path: "/b/config.yaml", Size: 30, ModTime: "2024-01-03 00:00:00", MatchCount: 1, Keyword: "config", FileType: "f"},
+		{Path: "/a/config", Size: 10, ModTime: "2024-01-01 00:00:00", MatchCount: 0, Keyword: "config", FileType: "f"},
+		{Path: "/a/old_config.bak", Size: 30, ModTime: "2024-01-02 00:00:00", MatchCount: 5, Keyword: "config", FileType: "f"},
+		{Path: "/b/lib", Size: 4096, ModTime: "2024-01-02 00:00:00", MatchCount: 0, Keyword: "config", FileType: "d"},
+	}
+}
+
+func TestOrderResults(t *testing.T) {
+	tests := []struct {
+		sortKey string
+		reverse bool
+		want    []string
+	}{
+		{"", false, []string{"/a/config", "/a/old_config.bak", "/b/config.yaml", "/b/lib"}},
+		{"", true, []string{"/b/lib", "/b/config.yaml", "/a/old_config.bak", "/a/config"}},
+		{"size", false, []string{"/a/config", "/a/old_config.bak", "/b/config.yaml", "/b/lib"}},
+		{"size", true, []string{"/b/lib", "/b/config.yaml", "/a/old_config.bak", "/a/config"}},
+		{"mtime", false, []string{"/a/config", "/a/old_config.bak", "/b/lib", "/b/config.yaml"}},
+		{"matches", false, []string{"/a/config", "/b/lib", "/b/config.yaml", "/a/old_config.bak"}},
+		// config.yaml 去掉扩展名后与关键字相同 100+1 分，config 100 分，old_config.bak 20+5 分，lib 0 分
+		{"score", false, []string{"/b/config.yaml", "/a/config", "/a/old_config.bak", "/b/lib"}},
+	}
+	for _, tt := range tests {
+		results := sortFixture()
+		OrderResults(results, tt.sortKey, tt.reverse, "")
+		if got := resultPaths(results); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("OrderResults(%q, reverse=%v) = %v, want %v", tt.sortKey, tt.reverse, got, tt.want)
+		}
+	}
+}
+
+func TestGroupResults(t *testing.T) {
+	tests := []struct {
+		groupBy string
+		sortKey string
+		want    []ResultGroup
+	}{
+		{"dir", "size", []ResultGroup{
+			{Key: "/a", Count: 2, Size: 40},
+			{Key: "/b", Count: 2, Size: 4126},
+		}},
+		{"ext", "", []ResultGroup{
+			{Key: "(无扩展名)", Count: 2, Size: 4106},
+			{Key: ".bak", Count: 1, Size: 30},
+			{Key: ".yaml", Count: 1, Size: 30},
+		}},
+		{"type", "", []ResultGroup{
+			{Key: "d", Count: 1, Size: 4096},
+			{Key: "f", Count: 3, Size: 70},
+		}},
+	}
+	for _, tt := range tests {
+		results := sortFixture()
+		OrderResults(results, tt.sortKey, false, tt.groupBy)
+		groups := GroupResults(results, tt.groupBy)
+		for i := range groups {
+			groups[i].Results = nil
+		}
+		if !reflect.DeepEqual(groups, tt.want) {
+			t.Errorf("GroupResults(%q) = %+v, want %+v", tt.groupBy, groups, tt.want)
+		}
+	}
+
+	// 组内保持排序顺序
+	results := sortFixture()
+	OrderResults(results, "size", true, "dir")
+	if got, want := resultPaths(results), []string{"/a/old_config.bak", "/a/config", "/b/lib", "/b/config.yaml"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("分组后按大小倒序 = %v, want %v", got, want)
+	}
+}
+
+func TestValidateOrder(t *testing.T) {
+	tests := []struct {
+		sortKey, groupBy string
+		ok               bool
+	}{
+		{"", "", true},
+		{"score", "owner", true},
+		{"name", "", false},
+		{"", "size", false},
+	}
+	for _, tt := range tests {
+		if err := ValidateOrder(tt.sortKey, tt.groupBy); (err == nil) != tt.ok {
+			t.Errorf("ValidateOrder(%q, %q) = %v, want ok=%v", tt.sortKey, tt.groupBy, err, tt.ok)
+		}
+	}
+}
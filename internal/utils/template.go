@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// TemplateView -format template 中模板可使用的字段
+// 内嵌 SearchResult 的全部字段（Path、Type、FileType、Size、ModTime、Permissions、MimeType、
+// LinkTarget、MatchType、MatchCount、Content、Keyword、Matches 等）；
+// 内容匹配的结果每个匹配行执行一次模板，Line、Column、Text、Context 为当前匹配行的信息，
+// 其他结果只执行一次，这些字段为零值
+type TemplateView struct {
+	*SearchResult
+	Line    int      // 当前匹配行的行号，从 1 开始
+	Column  int      // 当前匹配行第一处匹配的列号，从 1 开始
+	Columns []int    // 当前匹配行每处匹配的列号
+	Text    string   // 当前匹配行的内容
+	Context []string // 当前匹配行及其上下文
+}
+
+// templateColors color 函数可用的颜色名
+var templateColors = map[string]string{
+	"red":     ColorRed,
+	"green":   ColorGreen,
+	"yellow":  ColorYellow,
+	"blue":    ColorBlue,
+	"magenta": ColorMagenta,
+	"cyan":    ColorCyan,
+	"white":   ColorWhite,
+	"bold":    ColorBold,
+}
+
+// templateFuncs 模板的辅助函数
+var templateFuncs = template.FuncMap{
+	// size 格式化文件大小，如 1.5 MB
+	"size": FormatFileSize,
+	// rel 转换为相对于当前目录的路径，无法转换时原样返回
+	"rel": func(path string) string {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return path
+		}
+		wd, err := os.Getwd()
+		if err != nil {
+			return path
+		}
+		if rel, err := filepath.Rel(wd, abs); err == nil {
+			return rel
+		}
+		return path
+	},
+	"base": filepath.Base,
+	"dir":  filepath.Dir,
+	// color 用颜色包裹文本，如 {{color "red" .Path}}
+	"color": func(name, text string) (string, error) {
+		code, ok := templateColors[name]
+		if !ok {
+			return "", fmt.Errorf("未知的颜色: %s", name)
+		}
+		return code + text + ColorReset, nil
+	},
+	// highlight 高亮文本中的关键字
+	"highlight": HighlightKeyword,
+	// json 转换为 JSON，字符串会加上引号并转义
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"join": strings.Join,
+}
+
+// SetTemplate 设置 -format template 使用的模板，每个结果输出模板执行结果并换行（-0 时为 NUL）
+// 这里只检查语法；字段和索引是否有效取决于具体结果，执行出错时在写入该结果时报告
+func (om *OutputManager) SetTemplate(text string) error {
+	tmpl, err := template.New("output").Funcs(templateFuncs).Parse(strings.TrimSuffix(text, "\n"))
+	if err != nil {
+		return fmt.Errorf("解析输出模板失败: %v", err)
+	}
+
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	om.template = tmpl
+	return nil
+}
+
+// writeTemplate 按模板写入，内容匹配的结果每个匹配行写入一行
+// 模板对某个结果执行失败时提示该结果的路径并跳过，不影响其他结果，退出时通过 WriteErr 返回错误码
+func (om *OutputManager) writeTemplate(result *SearchResult) error {
+	if om.template == nil {
+		return fmt.Errorf("未指定输出模板 (-template 或 -template-file)")
+	}
+
+	views := []TemplateView{{SearchResult: result}}
+	if len(result.Matches) > 0 {
+		views = views[:0]
+		for _, match := range result.Matches {
+			view := TemplateView{
+				SearchResult: result,
+				Line:         match.Line,
+				Columns:      match.Columns,
+				Text:         match.Text,
+				Context:      match.Context,
+			}
+			if len(match.Columns) > 0 {
+				view.Column = match.Columns[0]
+			}
+			views = append(views, view)
+		}
+	}
+
+	var buf strings.Builder
+	for _, view := range views {
+		// 先写入临时缓冲，执行失败时不输出半行
+		var line strings.Builder
+		if err := om.template.Execute(&line, view); err != nil {
+			err = fmt.Errorf("执行输出模板失败: %s: %v", result.Path, err)
+			PrintError("%v", err)
+			if om.writeErr == nil {
+				om.writeErr = err
+			}
+			continue
+		}
+		buf.WriteString(line.String())
+		buf.WriteString(om.terminator())
+	}
+	_, err := io.WriteString(om.file, buf.String())
+	return err
+}
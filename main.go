@@ -1,13 +1,21 @@
 package main
 
 import (
+	"context"
 	"file-finder/internal/finder"
+	"file-finder/internal/search"
 	"file-finder/internal/utils"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -35,6 +43,12 @@ const fullUsage = `使用方法: finder [选项]
   -d, -dir string        搜索目录 (默认: ".")
   -g, -global            在根目录下进行全局搜索
   -D, -depth int         限制搜索深度 (默认: -1, 不限制)
+  -L, -follow            跟随符号链接进入目录，按设备号+inode检测循环，
+                         失效的链接在结果末尾单独列出
+  -xdev                  不进入与起始目录不同的文件系统
+  -include-fs string     显式包含默认跳过的文件系统类型，逗号分隔
+                         (默认跳过 proc/sysfs/cgroup 等伪文件系统及 nfs/cifs/fuse.* 等远程文件系统)
+  -all-fs                不跳过任何文件系统类型
 
 内容搜索选项:
   -m, -mode string       搜索模式 (默认: filename)
@@ -42,28 +56,97 @@ const fullUsage = `使用方法: finder [选项]
                          content  - 仅搜索文件内容
                          both     - 同时搜索文件名和内容
   -c, -context int       显示匹配内容的上下文行数 (默认: 2)
+  -A int                 匹配行之后的上下文行数 (默认同 -c)
+  -B int                 匹配行之前的上下文行数 (默认同 -c)
+                         终端中内容匹配的结果下方按 ripgrep 格式列出匹配行和上下文:
+                         "行号:内容" 为匹配行，"行号-内容" 为上下文，"--" 分隔不相邻的片段
   -M, -max-content-size int   内容搜索的最大文件大小，单位字节 (默认: 10MB)
   -s, -case-sensitive    启用大小写敏感搜索
 
 权限和时间搜索:
-  -p, -perm string       按权限搜索: r/w/rw
+  -p, -perm string       按权限位搜索: r/w/rw
+  -readable              仅搜索当前用户实际可读的文件 (考虑属主、属组和ACL)
+  -writable              仅搜索当前用户实际可写的文件
+  -executable            仅搜索当前用户实际可执行的文件
   -t, -time string       搜索指定时间后修改的文件 (格式: 2006-01-02)
 
 索引选项:
   -r, -rebuild-index     重建文件索引
 
 过滤选项:
-  -T, -types string      按文件类型过滤，逗号分隔 (如: go,txt,log)
+  -T, -types string      按扩展名过滤，逗号分隔 (如: go,txt,log)
+  -type string           按文件类型过滤，逗号分隔 (默认: 除目录外的所有类型)
+                         f=普通文件 d=目录 l=符号链接 s=套接字
+                         p=命名管道 b=块设备 c=字符设备
+  -mime string           按文件头检测的MIME类型过滤，逗号分隔，支持通配
+                         (如: text/*,application/x-sqlite3,application/zip)
   -S, -size int          限制文件大小（字节）
-  -e, -exclude string    排除目录，逗号分隔
+  -e, -exclude string    排除模式，逗号分隔，支持以下写法:
+                         名称或glob (如 tmp,*.bak)  匹配路径中任意一级名称
+                         相对路径 (如 src/tmp,./build)  相对搜索目录锚定
+                         绝对路径 (如 /var/log/journal)  与绝对路径锚定
+  -include string        包含模式，写法同 -exclude，指定后仅保留匹配的文件
+  -hidden string         隐藏文件策略 (默认: 包含隐藏文件，跳过 .cache/.npm 等噪声目录)
+                         include - 包含所有隐藏文件和目录
+                         exclude - 排除所有隐藏文件和目录
+                         only    - 只搜索隐藏文件及隐藏目录中的文件
+  -no-ignore             不读取 .gitignore/.ignore/.finderignore 忽略规则
+  -ignore-file string    额外的 gitignore 格式忽略文件，逗号分隔
 
 性能选项:
   -C, -concurrent        启用并发搜索 (默认: true)
-  -w, -workers int       并发工作协程数 (默认: 5)
+  -w, -workers int       并发工作协程数，同时作用于目录遍历、内容读取和建立索引
+                         (默认: 按CPU数和存储类型自动选择，机械硬盘上使用较少的读取协程)
+  -walk-workers int      单独指定目录遍历协程数 (默认同 -w)
+  -read-workers int      单独指定读取文件的协程数 (默认同 -w)
+  -max-results int       最多返回的结果数，达到后立即停止搜索 (默认: 0, 不限制)
+  -timeout duration      搜索超时时间 (如: 30s, 5m)，超时后输出已找到的结果
+  -nice                  低影响模式: 将I/O优先级设为 idle，内容搜索默认限速 10MB/s、200 个文件/s，
+                         读取延迟升高时自动减少并发读取的协程数
+  -read-bps int          每秒最多读取的文件字节数 (默认: 0, 不限制；-nice 时为 10MB)
+  -read-fps int          每秒最多打开读取的文件数 (默认: 0, 不限制；-nice 时为 200)
+  -checkpoint string     将搜索进度（已完成的目录和已找到的结果）定期保存到检查点文件
+  -resume string         从检查点文件继续中断的搜索，沿用原参数，命令行中的参数优先
 
 输出选项:
   -o, -output string     输出结果到指定文件
-  -f, -format string     输出格式: txt/json/csv (默认: txt)
+  -sort string           等待搜索结束后排序输出 (默认: 找到结果即输出)
+                         path    - 按路径
+                         size    - 按文件大小，从小到大
+                         mtime   - 按修改时间，从旧到新
+                         matches - 按内容匹配次数，从少到多
+                         score   - 按与关键字的相关度，从高到低
+  -reverse               反转排序顺序，未指定 -sort 时按路径倒序
+  -group-by string       按组输出结果，终端显示每组的结果数和大小，JSON 输出按组嵌套
+                         dir / ext / type / owner (目录/扩展名/文件类型/所有者)
+  -f, -format string     输出格式 (默认: txt)
+                         txt    - 每行一个结果的文本
+                         json   - 完整的JSON文档，包含运行信息(run)、结果数组(results)和统计(summary)
+                         ndjson - 每行一个紧凑的JSON结果对象，便于流式处理
+                         csv    - 表格，第一行为固定的表头，大小为字节数，时间为 RFC 3339 格式
+                         tsv    - 同 csv，以制表符分隔，字段中的特殊字符用反斜杠转义
+                         sarif  - SARIF 2.1.0 日志，每个内容匹配行为一个结果，可导入代码扫描平台
+                         html   - 单文件离线报告，结果表格可排序、筛选和按目录分组
+                         template - 按 -template 指定的模板输出，未指定 -o 时输出到终端代替结果表格
+                         grep     - 每个匹配行一行 path:line:col:text
+                         vimgrep  - 每处匹配一行 path:line:col:text，可用于编辑器的 quickfix 列表
+                         paths    - 每个文件只输出路径
+  -template string       输出模板 (Go text/template)，指定后默认使用 -f template
+                         内容匹配的结果每个匹配行输出一行，可用字段:
+                           .Path .Type .FileType .Size .ModTime .Permissions .MimeType .LinkTarget
+                           .MatchType .MatchCount .Keyword .Content .Matches
+                           .Line .Column .Columns .Text .Context (当前匹配行)
+                         辅助函数: size rel base dir join json highlight color
+                         如: -template '{{rel .Path}}:{{.Line}}:{{.Column}}: {{highlight .Text .Keyword}}'
+  -template-file string  从文件读取输出模板
+  -files-with-matches    只输出匹配的文件路径，同 -f paths (-l 已用于日志)
+  -q                     安静模式: 不输出横幅和进度信息，错误信息仍输出到标准错误
+  -count                 只输出结果总数，提示信息输出到标准错误
+  -0                     以 NUL 字符代替换行分隔每条结果，便于 xargs -0；单独使用时只输出路径
+                         grep、vimgrep、paths、template 格式未指定 -o 时结果输出到标准输出，
+                         横幅和提示信息输出到标准错误
+  -csv-delim string      CSV 分隔符，单个字符，\t 表示制表符 (默认: ,)
+  -csv-per-match         CSV/TSV 每个匹配行输出一行 (默认: 只记录第一个匹配行)
   -l, -log               记录调试日志到文件
 
 常用示例:
@@ -81,6 +164,7 @@ const fullUsage = `使用方法: finder [选项]
 
   5. 搜索并显示上下文:
      finder -k flag -m content -c 3 -g
+     finder -k flag -m content -B 1 -A 5 -g
 
   6. 区分大小写搜索:
      finder -k Flag -s -m both -g
@@ -97,14 +181,36 @@ const fullUsage = `使用方法: finder [选项]
   10. 搜索具有读写权限的文件:
       finder -p rw -g
 
-  11. 保存结果到JSON:
+  11. 搜索当前用户实际可写的文件（提权检查）:
+      finder -writable -d /etc
+
+  12. 搜索家目录中的 .env 等隐藏文件:
+      finder -k env -hidden only -d ~
+
+  13. 保存结果到JSON:
       finder -k flag -m both -f json -o result.json
 
+  14. 找到 10 个结果或 30 秒后停止:
+      finder -k flag -g -max-results 10 -timeout 30s
+
+  15. 可中断的全盘内容搜索，中断后继续:
+      finder -k password -m content -g -checkpoint scan.ckpt
+      finder -resume scan.ckpt
+
+  16. 在生产服务器上低影响地搜索内容（限速 5MB/s）:
+      finder -k password -m content -d /srv -nice -read-bps 5242880
+
 注意事项:
   1. 首次使用建议先运行 -r -g 建立索引
   2. 索引会在30分钟后过期，需要重建
-  3. 全局搜索时会遍历所有目录
+  3. 全局搜索时会遍历所有目录，但在 Linux 上默认跳过 /proc、/sys 等伪文件系统
+     和网络挂载，可配合 -xdev 限制在根文件系统内
   4. 建议使用 -T 和 -S 选项限制搜索范围
+  5. 默认遵循各目录下的 .gitignore、.ignore 和 .finderignore 规则，
+     .finderignore 优先级最高，可用 -no-ignore 关闭
+  6. 搜索过程中按 Ctrl-C 会停止搜索并输出已找到的结果，再次按 Ctrl-C 立即退出
+  7. 检查点支持内容、权限和时间搜索；文件名搜索依赖索引，继续时会重新构建索引
+  8. 退出码与 grep 一致: 找到结果时为 0，未找到时为 1，出错时为 2
 `
 
 // 获取 Windows 系统的所有驱动器
@@ -120,41 +226,46 @@ func getWindowsDrives() []string {
 }
 
 // 执行搜索并返回结果
-func executeSearchForPath(keyword *string, permType *string, timeLimit *string, config *finder.SearchConfig) (map[string]finder.FileInfo, error) {
+// sink 不为 nil 时结果由查找函数流式写入 sink，返回值为空
+func executeSearchForPath(ctx context.Context, keyword *string, permType *string, timeLimit *string, config *finder.SearchConfig, sink *resultSink) (map[string]finder.FileInfo, error) {
 	results := make(map[string]finder.FileInfo)
 
+	// 流式输出时统计每种搜索找到的结果数
+	var found int64
+	if sink != nil {
+		config.OnResult = func(info finder.FileInfo) {
+			atomic.AddInt64(&found, 1)
+			sink.add(info)
+		}
+		defer func() { config.OnResult = nil }()
+	}
+	foundCount := func(n int) int64 {
+		if sink != nil {
+			return atomic.SwapInt64(&found, 0)
+		}
+		return int64(n)
+	}
+
 	if *keyword != "" {
 		utils.PrintInfo("开始关键字搜索: %s", *keyword)
-		keywordResults, err := finder.FindFilesByKeyword(*keyword, config)
+		keywordResults, err := finder.FindFilesByKeyword(ctx, *keyword, config)
 		if err != nil {
 			return nil, fmt.Errorf("查找关键字文件出错: %v", err)
 		}
 		for k, v := range keywordResults {
 			results[k] = v
 		}
-		utils.PrintSuccess("关键字搜索完成，找到 %d 个结果", len(keywordResults))
+		utils.PrintSuccess("关键字搜索完成，找到 %d 个结果", foundCount(len(keywordResults)))
 	}
 
-	if *permType != "" {
-		utils.PrintInfo("开始权限搜索: %s", *permType)
-		files, err := finder.FindFilesByPermission(*permType, config)
+	if *permType != "" || config.HasAccessFilter() {
+		utils.PrintInfo("开始权限搜索: %s", describePermFilter(*permType, config))
+		files, err := finder.FindFilesByPermission(ctx, *permType, config)
 		if err != nil {
 			return nil, fmt.Errorf("查找权限文件出错: %v", err)
 		}
-		for _, file := range files {
-			if _, exists := results[file]; !exists {
-				info, err := os.Stat(file)
-				if err != nil {
-					continue
-				}
-				fileInfo, err := finder.GetFileInfo(file, info, config)
-				if err != nil {
-					continue
-				}
-				results[file] = fileInfo
-			}
-		}
-		utils.PrintSuccess("权限搜索完成，找到 %d 个结果", len(files))
+		mergePaths(results, files, config)
+		utils.PrintSuccess("权限搜索完成，找到 %d 个结果", foundCount(len(files)))
 	}
 
 	if *timeLimit != "" {
@@ -163,66 +274,339 @@ func executeSearchForPath(keyword *string, permType *string, timeLimit *string,
 			return nil, fmt.Errorf("时间格式错误: %v", err)
 		}
 		utils.PrintInfo("开始时间搜索: %s 之后的文件", *timeLimit)
-		files, err := finder.FindModifiedFiles(limitTime, config)
+		files, err := finder.FindModifiedFiles(ctx, limitTime, config)
 		if err != nil {
 			return nil, fmt.Errorf("查找修改文件出错: %v", err)
 		}
-		for _, file := range files {
-			if _, exists := results[file]; !exists {
-				info, err := os.Stat(file)
-				if err != nil {
-					continue
-				}
-				fileInfo, err := finder.GetFileInfo(file, info, config)
-				if err != nil {
-					continue
-				}
-				results[file] = fileInfo
+		mergePaths(results, files, config)
+		utils.PrintSuccess("时间搜索完成，找到 %d 个结果", foundCount(len(files)))
+	}
+
+	return results, nil
+}
+
+// mergePaths 将按路径返回的结果（权限、时间搜索）合并到结果中
+func mergePaths(results map[string]finder.FileInfo, files []string, config *finder.SearchConfig) {
+	for _, file := range files {
+		if _, exists := results[file]; !exists {
+			info, err := finder.StatEntry(file, config)
+			if err != nil {
+				continue
+			}
+			fileInfo, err := finder.GetFileInfo(file, info, config)
+			if err != nil {
+				continue
 			}
+			results[file] = fileInfo
 		}
-		utils.PrintSuccess("时间搜索完成，找到 %d 个结果", len(files))
 	}
+}
 
-	return results, nil
+// resultSink 流式输出时接收查找函数找到的结果，按路径去重后立即写入输出
+// 多种搜索条件找到同一文件时只输出第一次，达到 -max-results 后丢弃后续结果
+type resultSink struct {
+	mu      sync.Mutex
+	keyword string
+	max     int
+	seen    map[string]bool
+	count   int
+}
+
+// newResultSink 创建流式结果接收器
+func newResultSink(keyword string, max int) *resultSink {
+	return &resultSink{keyword: keyword, max: max, seen: make(map[string]bool)}
+}
+
+// add 输出一个结果，可被多个协程并发调用
+func (s *resultSink) add(info finder.FileInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen[info.Path] || (s.max > 0 && s.count >= s.max) {
+		return
+	}
+	s.seen[info.Path] = true
+	s.count++
+	utils.GlobalOutputManager.AddResult(convertResult(info, s.keyword))
+}
+
+// full 是否已达到 -max-results 限制
+func (s *resultSink) full() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.max > 0 && s.count >= s.max
+}
+
+// reportInterrupted 搜索被 Ctrl-C 或 -timeout 中断时提示结果不完整，并记录到输出文件的运行统计中
+func reportInterrupted(ctx context.Context) {
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		utils.PrintWarning("搜索超时，以下为已找到的部分结果")
+		utils.GlobalOutputManager.SetStatus(utils.StatusTimeout)
+	case context.Canceled:
+		utils.PrintWarning("搜索被中断，以下为已找到的部分结果")
+		utils.GlobalOutputManager.SetStatus(utils.StatusInterrupted)
+	}
+}
+
+// setRunInfo 将搜索条件和配置记录到输出文件的运行信息中
+func setRunInfo(keyword, permType, timeLimit string, timeout time.Duration, config *finder.SearchConfig) {
+	query := utils.RunQuery{
+		Keyword:       keyword,
+		Mode:          config.SearchMode,
+		CaseSensitive: config.CaseSensitive,
+		Permission:    permType,
+		Readable:      config.Readable,
+		Writable:      config.Writable,
+		Executable:    config.Executable,
+		ModifiedAfter: timeLimit,
+	}
+	runConfig := utils.RunConfig{
+		StartDir:       config.StartDir,
+		Global:         config.GlobalSearch,
+		MaxDepth:       config.MaxDepth,
+		Extensions:     config.FileTypes,
+		EntryTypes:     config.EntryTypes,
+		MimeTypes:      config.MimeTypes,
+		Exclude:        config.ExcludeDirs,
+		Include:        config.IncludeGlobs,
+		Hidden:         config.HiddenMode,
+		FollowLinks:    config.FollowLinks,
+		SizeLimit:      config.SizeLimit,
+		MaxContentSize: config.MaxContentSize,
+		MaxResults:     config.MaxResults,
+		NoIgnore:       config.NoIgnore,
+		IgnoreFiles:    config.IgnoreFiles,
+		SameFilesystem: config.SameFilesystem,
+	}
+	if timeout > 0 {
+		runConfig.Timeout = timeout.String()
+	}
+	utils.GlobalOutputManager.SetRunInfo(query, runConfig)
+}
+
+// reportThroughput 输出内容搜索的实际读取吞吐量
+func reportThroughput(config *finder.SearchConfig) {
+	if summary := config.Throttle.Summary(); summary != "" {
+		utils.PrintInfo("%s", summary)
+	}
+}
+
+// stripCheckpointArgs 去掉命令行参数中的 -checkpoint 和 -resume，其余参数原样保存到检查点
+func stripCheckpointArgs(args []string) []string {
+	var kept []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || !strings.HasPrefix(arg, "-") {
+			// flag 包在第一个非选项参数处停止解析，其后的参数原样保留
+			return append(kept, args[i:]...)
+		}
+		// 不是 -name=value 形式且不是布尔选项时，下一个参数为选项的值
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		n := 1
+		if !hasValue && !isBoolFlag(name) && i+1 < len(args) {
+			n = 2
+		}
+		if name != "checkpoint" && name != "resume" {
+			kept = append(kept, args[i:i+n]...)
+		}
+		i += n - 1
+	}
+	return kept
+}
+
+// isBoolFlag 判断选项是否为不需要参数值的布尔选项
+func isBoolFlag(name string) bool {
+	f := flag.CommandLine.Lookup(name)
+	if f == nil {
+		return false
+	}
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// closeCheckpoint 保存检查点的最终状态，搜索未完成时提示如何继续
+func closeCheckpoint(ctx context.Context, checkpoint *finder.Checkpoint, path string) {
+	if checkpoint == nil {
+		return
+	}
+	if err := checkpoint.Close(); err != nil {
+		utils.PrintWarning("保存检查点失败: %v", err)
+		return
+	}
+	if ctx.Err() != nil {
+		utils.PrintInfo("搜索进度已保存到 %s，使用 -resume %s 继续", path, path)
+	}
+}
+
+// limitReached 是否已达到 -max-results 限制
+func limitReached(results map[string]finder.FileInfo, config *finder.SearchConfig) bool {
+	return config.MaxResults > 0 && len(results) >= config.MaxResults
+}
+
+// truncateResults 按 -max-results 截断合并后的结果
+// 多种搜索条件的结果合并后可能超出限制，按路径排序后保留前 N 个以保证输出稳定
+func truncateResults(results map[string]finder.FileInfo, config *finder.SearchConfig) map[string]finder.FileInfo {
+	if !limitReached(results, config) || len(results) == config.MaxResults {
+		return results
+	}
+	paths := make([]string, 0, len(results))
+	for path := range results {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	truncated := make(map[string]finder.FileInfo, config.MaxResults)
+	for _, path := range paths[:config.MaxResults] {
+		truncated[path] = results[path]
+	}
+	return truncated
+}
+
+// describePermFilter 生成权限搜索条件的描述
+func describePermFilter(permType string, config *finder.SearchConfig) string {
+	var parts []string
+	if permType != "" {
+		parts = append(parts, "权限位="+permType)
+	}
+	if config.Readable {
+		parts = append(parts, "可读")
+	}
+	if config.Writable {
+		parts = append(parts, "可写")
+	}
+	if config.Executable {
+		parts = append(parts, "可执行")
+	}
+	return strings.Join(parts, ",")
 }
 
-// convertToSearchResults 将FileInfo转换为SearchResult
+// convertToSearchResults 将FileInfo转换为SearchResult，按路径排序以保证输出稳定
 func convertToSearchResults(results map[string]finder.FileInfo, keyword string) []*utils.SearchResult {
+	paths := make([]string, 0, len(results))
+	for path := range results {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
 	var searchResults []*utils.SearchResult
-	for path, info := range results {
-		resultType := utils.FILE_FOUND
-		if info.MatchType == "content" {
-			resultType = utils.CONTENT_MATCH
+	for _, path := range paths {
+		searchResults = append(searchResults, convertResult(results[path], keyword))
+	}
+	return searchResults
+}
+
+// convertResult 将单个FileInfo转换为SearchResult
+func convertResult(info finder.FileInfo, keyword string) *utils.SearchResult {
+	resultType := utils.FILE_FOUND
+	if info.MatchType == "content" {
+		resultType = utils.CONTENT_MATCH
+	}
+	if info.LinkBroken {
+		resultType = utils.BROKEN_LINK
+	}
+
+	return &utils.SearchResult{
+		Time:        time.Now(),
+		Type:        resultType,
+		Path:        info.Path,
+		Size:        info.Size,
+		ModTime:     info.ModTime,
+		Permissions: info.Permissions,
+		FileType:    info.Type,
+		MimeType:    info.MimeType,
+		LinkTarget:  info.LinkTarget,
+		MatchType:   info.MatchType,
+		MatchCount:  info.MatchCount,
+		Content:     info.Content,
+		Keyword:     keyword,
+		Details: map[string]interface{}{
+			"match_lines": info.MatchLines,
+			"context":     info.Context,
+		},
+		Matches: convertMatches(info.Matches),
+	}
+}
+
+// loadTemplate 读取 -template 或 -template-file 指定的输出模板
+func loadTemplate(text, path string) error {
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("读取模板文件失败: %v", err)
 		}
+		text = string(data)
+	}
+	if text == "" {
+		return fmt.Errorf("-format template 需要指定 -template 或 -template-file")
+	}
+	return utils.GlobalOutputManager.SetTemplate(text)
+}
 
-		result := &utils.SearchResult{
-			Time:        time.Now(),
-			Type:        resultType,
-			Path:        path,
-			Size:        info.Size,
-			ModTime:     info.ModTime,
-			Permissions: info.Permissions,
-			MatchType:   info.MatchType,
-			MatchCount:  info.MatchCount,
-			Content:     info.Content,
-			Keyword:     keyword,
-			Details: map[string]interface{}{
-				"match_lines": info.MatchLines,
-				"context":     info.Context,
-			},
+// orderResults 按 -sort、-reverse 和 -group-by 排列结果，按所有者分组时先查询所有者
+func orderResults(results []*utils.SearchResult, sortKey string, reverse bool, groupBy string) {
+	if groupBy == "owner" {
+		for _, result := range results {
+			result.Owner = finder.FileOwner(result.Path)
 		}
-		searchResults = append(searchResults, result)
 	}
-	return searchResults
+	utils.OrderResults(results, sortKey, reverse, groupBy)
+}
+
+// convertMatches 转换匹配行，列号转换为从 1 开始
+func convertMatches(matches []search.ContextMatch) []utils.MatchLine {
+	if len(matches) == 0 {
+		return nil
+	}
+	lines := make([]utils.MatchLine, len(matches))
+	for i, match := range matches {
+		columns := make([]int, len(match.LineMatch.Positions))
+		for j, pos := range match.LineMatch.Positions {
+			columns[j] = pos + 1
+		}
+		lines[i] = utils.MatchLine{
+			Line:         match.LineMatch.LineNumber,
+			Columns:      columns,
+			Text:         match.LineMatch.Line,
+			Context:      match.Context,
+			ContextStart: match.ContextStart,
+		}
+	}
+	return lines
 }
 
 func main() {
+	os.Exit(run())
+}
+
+// 退出码，与 grep 一致
+const (
+	exitSuccess = 0 // 找到结果，或无需搜索（显示帮助、重建索引）
+	exitNoMatch = 1 // 未找到结果
+	exitError   = 2 // 参数错误或搜索出错
+)
+
+// exitStatus 根据是否找到结果返回退出码，写入结果失败时返回 exitError
+func exitStatus() int {
+	if utils.GlobalOutputManager.WriteErr() != nil {
+		return exitError
+	}
+	if utils.GlobalOutputManager.Total() > 0 {
+		return exitSuccess
+	}
+	return exitNoMatch
+}
+
+// run 解析参数并执行搜索，返回退出码
+func run() (code int) {
 	config := finder.NewDefaultConfig()
 
 	// 设置自定义帮助信息
 	flag.Usage = func() {
 		utils.PrintSimpleBanner()
-		fmt.Println(simpleUsage)
+		fmt.Print(simpleUsage)
 	}
 
 	// 基本参数（支持长短选项）
@@ -239,6 +623,8 @@ func main() {
 	flag.StringVar(&config.SearchMode, "m", "filename", "搜索模式: filename/content/both")
 	flag.IntVar(&config.ContextLines, "context", 2, "显示匹配内容的上下文行数")
 	flag.IntVar(&config.ContextLines, "c", 2, "显示匹配内容的上下文行数")
+	flag.IntVar(&config.ContextAfter, "A", -1, "匹配行之后的上下文行数 (默认同 -c)")
+	flag.IntVar(&config.ContextBefore, "B", -1, "匹配行之前的上下文行数 (默认同 -c)")
 	flag.Int64Var(&config.MaxContentSize, "max-content-size", 10*1024*1024, "内容搜索的最大文件大小(字节)")
 	flag.Int64Var(&config.MaxContentSize, "M", 10*1024*1024, "内容搜索的最大文件大小(字节)")
 	flag.BoolVar(&config.CaseSensitive, "case-sensitive", false, "是否区分大小写")
@@ -255,8 +641,10 @@ func main() {
 	flag.IntVar(&config.MaxDepth, "D", -1, "最大搜索深度")
 	flag.BoolVar(&config.Concurrent, "concurrent", true, "是否使用并发搜索")
 	flag.BoolVar(&config.Concurrent, "C", true, "是否使用并发搜索")
-	flag.IntVar(&config.MaxWorkers, "workers", 5, "并发工作协程数")
-	flag.IntVar(&config.MaxWorkers, "w", 5, "并发工作协程数")
+	flag.IntVar(&config.MaxWorkers, "workers", 0, "并发工作协程数，同时作用于遍历和读取 (默认按CPU数和存储类型自动选择)")
+	flag.IntVar(&config.MaxWorkers, "w", 0, "并发工作协程数，同时作用于遍历和读取 (默认按CPU数和存储类型自动选择)")
+	flag.IntVar(&config.WalkWorkers, "walk-workers", 0, "目录遍历协程数 (默认同 -w)")
+	flag.IntVar(&config.ReadWorkers, "read-workers", 0, "读取文件协程数，用于内容搜索和建立索引 (默认同 -w)")
 	flag.Int64Var(&config.SizeLimit, "size", -1, "文件大小限制(字节)")
 	flag.Int64Var(&config.SizeLimit, "S", -1, "文件大小限制(字节)")
 
@@ -264,9 +652,34 @@ func main() {
 	var fileTypes string
 	flag.StringVar(&fileTypes, "types", "", "文件类型过滤(逗号分隔，如: go,txt)")
 	flag.StringVar(&fileTypes, "T", "", "文件类型过滤(逗号分隔，如: go,txt)")
+	var entryTypes string
+	flag.StringVar(&entryTypes, "type", "", "按文件类型过滤(逗号分隔): f/d/l/s/p/b/c")
+	var mimeTypes string
+	flag.StringVar(&mimeTypes, "mime", "", "按内容检测的MIME类型过滤(逗号分隔，如: text/*,application/x-sqlite3)")
 	var excludeDirs string
-	flag.StringVar(&excludeDirs, "exclude", "", "排除的目录(逗号分隔)")
-	flag.StringVar(&excludeDirs, "e", "", "排除的目录(逗号分隔)")
+	flag.StringVar(&excludeDirs, "exclude", "", "排除模式(逗号分隔): 名称、glob 或锚定路径")
+	flag.StringVar(&excludeDirs, "e", "", "排除模式(逗号分隔): 名称、glob 或锚定路径")
+	var includeGlobs string
+	flag.StringVar(&includeGlobs, "include", "", "包含模式(逗号分隔): 仅保留匹配的文件")
+
+	// 符号链接参数
+	flag.BoolVar(&config.FollowLinks, "follow", false, "跟随符号链接（带循环检测）")
+	flag.BoolVar(&config.FollowLinks, "L", false, "跟随符号链接（带循环检测）")
+
+	// 文件系统边界参数
+	flag.BoolVar(&config.SameFilesystem, "xdev", false, "不进入其他文件系统")
+	flag.BoolVar(&config.AllFilesystems, "all-fs", false, "不跳过 proc/sysfs/nfs 等伪文件系统和远程文件系统")
+	var includeFS string
+	flag.StringVar(&includeFS, "include-fs", "", "显式包含的文件系统类型(逗号分隔，如: nfs,fuse.sshfs)")
+
+	// 隐藏文件参数
+	var hiddenMode string
+	flag.StringVar(&hiddenMode, "hidden", "", "隐藏文件策略: include/exclude/only (默认跳过常见缓存目录)")
+
+	// 忽略文件参数
+	flag.BoolVar(&config.NoIgnore, "no-ignore", false, "不读取 .gitignore/.ignore/.finderignore 忽略规则")
+	var ignoreFiles string
+	flag.StringVar(&ignoreFiles, "ignore-file", "", "额外的 gitignore 格式忽略文件(逗号分隔)")
 
 	// 重建索引参数
 	var rebuildIndex bool
@@ -283,21 +696,60 @@ func main() {
 	flag.StringVar(&outputPath, "o", "", "输出结果到指定文件")
 	flag.StringVar(&outputPath, "output", "", "输出结果到指定文件")
 	var outputFormat string
-	flag.StringVar(&outputFormat, "of", "txt", "输出格式: txt, json, csv")
-	flag.StringVar(&outputFormat, "format", "txt", "输出格式: txt, json, csv")
-	flag.StringVar(&outputFormat, "f", "txt", "输出格式: txt, json, csv")
+	flag.StringVar(&outputFormat, "of", "txt", "输出格式: txt, json, ndjson, csv, tsv, sarif, html, template, grep, vimgrep, paths")
+	flag.StringVar(&outputFormat, "format", "txt", "输出格式: txt, json, ndjson, csv, tsv, sarif, html, template, grep, vimgrep, paths")
+	flag.StringVar(&outputFormat, "f", "txt", "输出格式: txt, json, ndjson, csv, tsv, sarif, html, template, grep, vimgrep, paths")
+	var csvDelimiter string
+	flag.StringVar(&csvDelimiter, "csv-delim", ",", "CSV 分隔符，单个字符，\\t 表示制表符")
+	var csvPerMatch bool
+	flag.BoolVar(&csvPerMatch, "csv-per-match", false, "CSV/TSV 每个匹配行输出一行")
+	var templateText, templateFile string
+	flag.StringVar(&templateText, "template", "", "输出模板 (Go text/template)，如 '{{.Path}}:{{.Line}}:{{.Text}}'")
+	flag.StringVar(&templateFile, "template-file", "", "从文件读取输出模板")
+	var quiet, countOnly bool
+	flag.BoolVar(&quiet, "q", false, "安静模式：不输出横幅、进度和统计信息，标准输出只有结果")
+	flag.BoolVar(&countOnly, "count", false, "只输出结果总数")
+	var filesWithMatches, nullSeparated bool
+	flag.BoolVar(&filesWithMatches, "files-with-matches", false, "只输出匹配的文件路径，同 -f paths")
+	flag.BoolVar(&nullSeparated, "0", false, "以 NUL 字符代替换行分隔每条结果，便于 xargs -0")
 
 	// 权限参数
 	var permType string
 	flag.StringVar(&permType, "perm", "", "按权限搜索: r/w/rw")
 	flag.StringVar(&permType, "p", "", "按权限搜索: r/w/rw")
+	flag.BoolVar(&config.Readable, "readable", false, "仅搜索当前用户实际可读的文件")
+	flag.BoolVar(&config.Writable, "writable", false, "仅搜索当前用户实际可写的文件")
+	flag.BoolVar(&config.Executable, "executable", false, "仅搜索当前用户实际可执行的文件")
+
+	// 结果数量和超时参数
+	flag.IntVar(&config.MaxResults, "max-results", 0, "最多返回的结果数，达到后停止搜索 (0 表示不限制)")
+	var timeout time.Duration
+	flag.DurationVar(&timeout, "timeout", 0, "搜索超时时间 (如: 30s, 5m)，超时后输出已找到的结果")
+
+	// 读取限速参数
+	flag.BoolVar(&config.Nice, "nice", false, "低影响模式：限制读取速度、降低I/O优先级并在磁盘繁忙时减少并发")
+	flag.Int64Var(&config.ReadBytesPerSec, "read-bps", 0, "每秒最多读取的文件字节数 (-nice 时默认 10MB)")
+	flag.IntVar(&config.ReadFilesPerSec, "read-fps", 0, "每秒最多打开读取的文件数 (-nice 时默认 200)")
+
+	// 结果输出顺序
+	var sortKey, groupBy string
+	var reverse bool
+	flag.StringVar(&sortKey, "sort", "", "等待搜索结束后排序输出: path/size/mtime/matches/score (默认找到即输出)")
+	flag.BoolVar(&reverse, "reverse", false, "反转排序顺序，未指定 -sort 时按路径倒序")
+	flag.StringVar(&groupBy, "group-by", "", "按组输出结果: dir/ext/type/owner")
+
+	// 断点续搜参数
+	var checkpointPath string
+	flag.StringVar(&checkpointPath, "checkpoint", "", "记录搜索进度的检查点文件，中断后可用 -resume 继续")
+	var resumePath string
+	flag.StringVar(&resumePath, "resume", "", "从检查点文件继续之前中断的搜索")
 
 	// 检查是否需要显示完整帮助信息（在flag.Parse之前检查）
 	for _, arg := range os.Args[1:] {
 		if arg == "-h" || arg == "--help" || arg == "-help" {
 			utils.PrintBanner()
-			fmt.Println(fullUsage)
-			return
+			fmt.Print(fullUsage)
+			return exitSuccess
 		}
 	}
 
@@ -307,8 +759,46 @@ func main() {
 	// 如果没有参数，显示简化帮助
 	if len(os.Args) == 1 {
 		utils.PrintSimpleBanner()
-		fmt.Println(simpleUsage)
-		return
+		fmt.Print(simpleUsage)
+		return exitSuccess
+	}
+
+	// 从检查点继续时，使用检查点中记录的原始参数，本次命令行中的参数优先（如 -timeout）
+	var checkpoint *finder.Checkpoint
+	if resumePath != "" {
+		cp, err := finder.LoadCheckpoint(resumePath)
+		if err != nil {
+			utils.PrintError("读取检查点失败: %v", err)
+			return exitError
+		}
+		if err := flag.CommandLine.Parse(cp.Args); err != nil {
+			return exitError
+		}
+		if err := flag.CommandLine.Parse(os.Args[1:]); err != nil {
+			return exitError
+		}
+		checkpoint = cp
+		checkpointPath = resumePath
+	} else if checkpointPath != "" {
+		checkpoint = finder.NewCheckpoint(checkpointPath, stripCheckpointArgs(os.Args[1:]))
+	}
+
+	// 结果逐行输出到标准输出时，横幅和提示信息改为输出到标准错误
+	if filesWithMatches || (nullSeparated && outputFormat == "txt") {
+		outputFormat = "paths"
+	}
+	if (templateText != "" || templateFile != "") && outputFormat == "txt" {
+		outputFormat = "template"
+	}
+	if nullSeparated && !utils.IsLineFormat(outputFormat) {
+		utils.PrintError("-0 仅适用于 grep、vimgrep、paths 和 template 格式")
+		return exitError
+	}
+	if countOnly || (outputPath == "" && utils.IsLineFormat(outputFormat)) {
+		utils.SetMessageOutput(os.Stderr)
+	}
+	if quiet {
+		utils.SetMessageOutput(io.Discard)
 	}
 
 	// 打印艺术字横幅
@@ -317,37 +807,122 @@ func main() {
 	// 初始化日志系统
 	if err := utils.InitLogger(enableLog); err != nil {
 		utils.PrintError("初始化日志失败: %v", err)
-		os.Exit(1)
+		return exitError
 	}
 	defer utils.CloseLogger()
 
 	// 初始化输出管理器
 	if err := utils.InitOutputManager(outputPath, outputFormat); err != nil {
 		utils.PrintError("初始化输出管理器失败: %v", err)
-		os.Exit(1)
+		return exitError
+	}
+	defer func() {
+		// HTML 报告、JSON 结尾等在关闭时写入，失败时同样返回错误码
+		if err := utils.GlobalOutputManager.Close(); err != nil {
+			utils.PrintError("写入输出文件失败: %v", err)
+			code = exitError
+		}
+	}()
+	delimiter, err := utils.ParseDelimiter(csvDelimiter)
+	if err == nil {
+		err = utils.GlobalOutputManager.SetCsvOptions(delimiter, csvPerMatch)
+	}
+	if err == nil && outputFormat == "template" {
+		err = loadTemplate(templateText, templateFile)
 	}
-	defer utils.GlobalOutputManager.Close()
+	if err != nil {
+		utils.PrintError("%v", err)
+		return exitError
+	}
+	utils.GlobalOutputManager.SetNullSeparated(nullSeparated)
+	utils.GlobalOutputManager.SetCountOnly(countOnly)
+	if err := utils.ValidateOrder(sortKey, groupBy); err != nil {
+		utils.PrintError("%v", err)
+		return exitError
+	}
+	utils.GlobalOutputManager.SetGroupBy(groupBy)
 
 	// 检查是否有任何有效的搜索参数
-	if keyword == "" && permType == "" && timeLimit == "" && !rebuildIndex {
-		utils.PrintError("请至少指定一个搜索条件（-k/-keyword、-p/-perm、-t/-time 或 -r/-rebuild-index）")
+	if keyword == "" && permType == "" && !config.HasAccessFilter() && timeLimit == "" && !rebuildIndex {
+		utils.PrintError("请至少指定一个搜索条件（-k/-keyword、-p/-perm、-readable/-writable/-executable、-t/-time 或 -r/-rebuild-index）")
 		fmt.Println("\n可用的命令行选项：")
 		flag.Usage()
-		return
+		return exitError
 	}
 
 	// 处理文件类型和排除目录
 	if fileTypes != "" {
 		config.FileTypes = strings.Split(fileTypes, ",")
 	}
+	if entryTypes != "" {
+		types, err := finder.ParseEntryTypes(entryTypes)
+		if err != nil {
+			utils.PrintError("%v", err)
+			return exitError
+		}
+		config.EntryTypes = types
+	}
+	if mimeTypes != "" {
+		config.MimeTypes = strings.Split(mimeTypes, ",")
+	}
 	if excludeDirs != "" {
 		config.ExcludeDirs = strings.Split(excludeDirs, ",")
 	}
 
+	mode, err := finder.ParseHiddenMode(hiddenMode)
+	if err != nil {
+		utils.PrintError("%v", err)
+		return exitError
+	}
+	config.HiddenMode = mode
+	if includeFS != "" {
+		config.IncludeFSTypes = finder.ParseFSTypes(includeFS)
+	}
+	if includeGlobs != "" {
+		config.IncludeGlobs = strings.Split(includeGlobs, ",")
+	}
+	if ignoreFiles != "" {
+		config.IgnoreFiles = strings.Split(ignoreFiles, ",")
+	}
+
 	// 添加默认排除的系统目录
 	config.ExcludeDirs = append(config.ExcludeDirs,
 		"$Recycle.Bin", "$RECYCLE.BIN", "System Volume Information")
 
+	// Ctrl-C 或超时后取消搜索，仍输出已找到的结果；再次 Ctrl-C 立即退出
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	// 低影响模式：降低I/O优先级，内容搜索按限速读取
+	if config.Nice {
+		if err := finder.SetIdleIOPriority(); err != nil {
+			utils.PrintWarning("设置I/O优先级失败: %v", err)
+		}
+	}
+	if checkpoint != nil {
+		if resumePath != "" {
+			utils.PrintInfo("从检查点 %s 继续搜索（创建于 %s）", resumePath, checkpoint.Created.Format("2006-01-02 15:04:05"))
+		}
+		config.Checkpoint = checkpoint
+		checkpoint.Start(10 * time.Second)
+	}
+
+	// 默认找到结果即输出，-sort、-reverse、-group-by 时缓冲全部结果排序后输出
+	var sink *resultSink
+	if sortKey == "" && !reverse && groupBy == "" && !rebuildIndex {
+		sink = newResultSink(keyword, config.MaxResults)
+		utils.GlobalOutputManager.SetStreaming(os.Stdout)
+	}
+
 	// 如果开启全局搜索，设置起始目录为根目录
 	if config.GlobalSearch {
 		if runtime.GOOS == "windows" {
@@ -355,10 +930,15 @@ func main() {
 			if len(drives) > 0 {
 				utils.PrintInfo("Windows系统，发现 %d 个驱动器", len(drives))
 				allResults := make(map[string]finder.FileInfo)
+				config.Throttle = finder.NewIOThrottle(config)
+				setRunInfo(keyword, permType, timeLimit, timeout, config)
 				for _, drive := range drives {
+					if ctx.Err() != nil || limitReached(allResults, config) || sink.full() {
+						break
+					}
 					utils.PrintInfo("正在搜索驱动器: %s", drive)
 					config.StartDir = drive
-					results, err := executeSearchForPath(&keyword, &permType, &timeLimit, config)
+					results, err := executeSearchForPath(ctx, &keyword, &permType, &timeLimit, config, sink)
 					if err != nil {
 						utils.PrintWarning("搜索驱动器 %s 时出错: %v", drive, err)
 						continue
@@ -367,40 +947,57 @@ func main() {
 						allResults[k] = v
 					}
 				}
+				closeCheckpoint(ctx, checkpoint, checkpointPath)
+				reportInterrupted(ctx)
+				reportThroughput(config)
 				// 转换并保存结果
-				searchResults := convertToSearchResults(allResults, keyword)
+				searchResults := convertToSearchResults(truncateResults(allResults, config), keyword)
+				orderResults(searchResults, sortKey, reverse, groupBy)
 				for _, result := range searchResults {
 					utils.GlobalOutputManager.AddResult(result)
 				}
 				utils.GlobalOutputManager.PrintResults(os.Stdout)
-				return
+				return exitStatus()
 			}
 		} else {
 			config.StartDir = "/"
 		}
 	}
 
+	// 限速器需在起始目录确定后创建，以便按存储类型选择读取协程数
+	config.Throttle = finder.NewIOThrottle(config)
+	setRunInfo(keyword, permType, timeLimit, timeout, config)
+
 	// 在参数解析添加
 	if rebuildIndex {
 		config.GlobalSearch = true // 重建索引时默认全局搜索
 		indexer := finder.GetIndexer()
 		utils.PrintInfo("开始重建文件索引...")
-		if err := indexer.BuildIndex(config.StartDir, config); err != nil {
+		if err := indexer.BuildIndex(ctx, config.StartDir, config); err != nil {
 			utils.PrintError("重建索引时出错: %v", err)
-			os.Exit(1)
+			return exitError
 		}
-		utils.PrintSuccess("索引重建完成")
-		return
+		if ctx.Err() != nil {
+			utils.PrintWarning("索引构建被中断，索引仅包含已遍历的部分")
+			return exitSuccess
+		}
+		stats := indexer.Stats()
+		utils.PrintSuccess("索引重建完成，共 %d 个条目（跳过 %d 个，错误 %d 个）", stats.Indexed, stats.Skipped, stats.Errors)
+		return exitSuccess
 	}
 
-	results, err := executeSearchForPath(&keyword, &permType, &timeLimit, config)
+	results, err := executeSearchForPath(ctx, &keyword, &permType, &timeLimit, config, sink)
+	closeCheckpoint(ctx, checkpoint, checkpointPath)
 	if err != nil {
 		utils.PrintError("搜索出错: %v", err)
-		os.Exit(1)
+		return exitError
 	}
+	reportInterrupted(ctx)
+	reportThroughput(config)
 
 	// 转换并保存结果
-	searchResults := convertToSearchResults(results, keyword)
+	searchResults := convertToSearchResults(truncateResults(results, config), keyword)
+	orderResults(searchResults, sortKey, reverse, groupBy)
 	for _, result := range searchResults {
 		utils.GlobalOutputManager.AddResult(result)
 	}
@@ -412,4 +1009,6 @@ func main() {
 	if outputPath != "" {
 		utils.PrintSuccess("结果已保存到: %s", outputPath)
 	}
+
+	return exitStatus()
 }